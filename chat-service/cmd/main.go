@@ -1,21 +1,31 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/zhanserikAmangeldi/chat-service/config"
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/bus"
 	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/handler"
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/presence"
 	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/repository"
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/storage"
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/userclient"
 	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
 	"github.com/zhanserikAmangeldi/chat-service/internal/core/service"
 	"github.com/zhanserikAmangeldi/chat-service/internal/middleware"
 	"github.com/zhanserikAmangeldi/chat-service/internal/migration"
+	"github.com/zhanserikAmangeldi/jwtauth"
 )
 
 func main() {
@@ -41,16 +51,138 @@ func main() {
 	log.Println("Migrations applied successfully")
 
 	// Initialize components
-	wsManager := websocket.NewClientManager()
+	wsManager := websocket.NewClientManagerWithConfig(websocket.ClientConfig{
+		SendBufferSize: cfg.WSSendBuffer,
+		OverflowPolicy: websocket.OverflowPolicy(cfg.WSOverflow),
+		PingInterval:   cfg.WSPingInterval,
+		PongWait:       cfg.WSPongWait,
+		WriteWait:      cfg.WSWriteWait,
+		MaxMessageSize: cfg.WSMaxMessage,
+	})
 	repo := repository.NewPostgresRepository(db)
-	chatService := service.NewChatService(repo, wsManager)
 
-	// WebSocket handler with simple JWT authentication (no Redis)
-	wsHandler := handler.NewWSHandler(wsManager, cfg.JWTSecret)
-	http.HandleFunc("/ws", wsHandler.HandleConnection)
+	// Redis backs the cross-replica delivery bus, presence and the token
+	// revocation list
+	var redisClient *redis.Client
+	if cfg.DeliveryBus == "redis" || cfg.Presence == "redis" || cfg.Revocation == "redis" {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr: cfg.GetRedisAddr(),
+			DB:   cfg.RedisDB,
+		})
+		defer redisClient.Close()
+
+		if err := redisClient.Ping(context.Background()).Err(); err != nil {
+			log.Fatalf("Unable to connect to Redis: %v", err)
+		}
+		log.Println("Connected to Redis")
+	}
+
+	// Delivery bus: in-memory for a single node, Redis pub/sub when running
+	// several replicas
+	var deliveryBus ports.DeliveryBus = wsManager
+	if cfg.DeliveryBus == "redis" {
+		redisBus := bus.NewRedisBus(redisClient, bus.DefaultChannel, wsManager)
+		go func() {
+			if err := redisBus.Run(context.Background()); err != nil {
+				log.Fatalf("Delivery bus stopped: %v", err)
+			}
+		}()
+		deliveryBus = redisBus
+	}
+
+	chatService := service.NewChatService(repo, deliveryBus)
+	chatService.SetTypingTimeout(cfg.TypingTimeout)
+	chatService.SetEditWindow(cfg.EditWindow)
+	chatService.SetAllowedReactions(cfg.Reactions)
+	chatService.SetMaxAttachmentSize(cfg.MaxAttachment)
+
+	// Presence: heartbeats in Redis, changes announced to the user service
+	if cfg.Presence == "redis" {
+		presenceStore := presence.NewRedisStore(redisClient)
+		chatService.SetPresence(presenceStore, presenceStore)
+		chatService.SetPresenceTimeouts(cfg.PresenceTTL, cfg.AwayAfter)
+		go chatService.RunPresenceSweeper(context.Background(), cfg.PresenceSweep)
+	}
+
+	// User lookups over gRPC: recipients must exist, the inbox shows names
+	// and avatars
+	if cfg.UserDirectory == "grpc" {
+		userClient, err := userclient.New(userclient.Config{
+			Addr:        cfg.UserGRPCAddr,
+			Timeout:     cfg.UserTimeout,
+			MaxAttempts: cfg.UserAttempts,
+			Token:       cfg.UserToken,
+		})
+		if err != nil {
+			log.Fatalf("Failed to set up user service client: %v", err)
+		}
+		defer userClient.Close()
+		chatService.SetUserDirectory(userClient)
+	}
+
+	// Attachment storage: local directory by default, any S3-compatible
+	// bucket for multi-node deployments
+	var blobStore ports.BlobStore
+	if cfg.Storage == "s3" {
+		blobStore, err = storage.NewS3Store(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		})
+	} else {
+		blobStore, err = storage.NewLocalStore(cfg.StoragePath)
+	}
+	if err != nil {
+		log.Fatalf("Failed to set up attachment storage: %v", err)
+	}
+	chatService.SetBlobStore(blobStore)
+
+	// Tokens revoked by logout in the user service are refused, and open
+	// sockets using them are closed. When Redis is unreachable, fail mode
+	// "open" lets tokens through and "closed" rejects them.
+	var revocations *middleware.RevocationList
+	if cfg.Revocation == "redis" {
+		revocations = middleware.NewRevocationList(redisClient, cfg.RevocationFail != "closed")
+		go func() {
+			if err := revocations.Run(context.Background()); err != nil {
+				log.Printf("Revocation subscriber stopped: %v", err)
+			}
+		}()
+	}
+
+	// Access tokens issued by the user service, checked for issuer and
+	// audience. With "jwks" they are verified against the public keys the
+	// user service publishes, cached and refetched for keys rotated in;
+	// "secret" shares its HS256 secret instead.
+	jwtConfig := jwtauth.Config{
+		Secret:           cfg.JWTSecret,
+		Issuer:           cfg.JWTIssuer,
+		Audience:         cfg.JWTAudience,
+		AcceptHS256Until: cfg.JWTAcceptHS256Until,
+	}
+	if cfg.JWTKeys == "jwks" {
+		keys := jwtauth.NewRemoteKeySet(cfg.JWKSURL, cfg.JWKSCacheTTL)
+		if err := keys.Refresh(); err != nil {
+			log.Printf("JWKS not fetched yet, will retry: %v", err)
+		}
+		jwtConfig.Keys = keys
+		log.Printf("Verifying tokens with keys from %s", cfg.JWKSURL)
+	}
+	verifier := jwtauth.NewVerifier(jwtConfig)
+
+	// WebSocket handler with JWT authentication
+	wsHandler := handler.NewWSHandler(wsManager, chatService, verifier)
+	wsHandler.SetRevocations(revocations, cfg.RevokeRecheck)
+
+	// Public endpoints get their own mux: http.DefaultServeMux carries
+	// /debug/vars, which only the admin listener serves
+	public := http.NewServeMux()
+	public.HandleFunc("/ws", wsHandler.HandleConnection)
 
 	// Health check endpoint
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	public.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -77,9 +209,13 @@ func main() {
 			return
 		}
 
+		// Either recipient_id (1:1 chat) or conversation_id (group chat) is set
 		type SendMessageRequest struct {
-			RecipientID int64  `json:"recipient_id"`
-			Content     string `json:"content"`
+			RecipientID     int64   `json:"recipient_id"`
+			ConversationID  int64   `json:"conversation_id"`
+			Content         string  `json:"content"`
+			ClientMessageID string  `json:"client_message_id"`
+			AttachmentIDs   []int64 `json:"attachment_ids"`
 		}
 
 		var req SendMessageRequest
@@ -89,9 +225,15 @@ func main() {
 		}
 
 		// Use authenticated user as sender
-		msg, err := chatService.SendMessage(r.Context(), userID, req.RecipientID, req.Content)
+		var msg *domain.Message
+		var err error
+		if req.ConversationID != 0 {
+			msg, err = chatService.SendGroupMessage(r.Context(), userID, req.ConversationID, req.Content, req.ClientMessageID, req.AttachmentIDs...)
+		} else {
+			msg, err = chatService.SendMessage(r.Context(), userID, req.RecipientID, req.Content, req.ClientMessageID, req.AttachmentIDs...)
+		}
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			handler.WriteServiceError(w, err)
 			return
 		}
 
@@ -122,36 +264,107 @@ func main() {
 		var conversationID int64
 		fmt.Sscanf(conversationIDStr, "%d", &conversationID)
 
-		// TODO: Verify user is participant in this conversation
-		_ = userID // Will use this for authorization check
+		cursor, direction, limit, err := handler.PageParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		messages, err := chatService.GetHistory(r.Context(), conversationID)
+		page, err := chatService.GetHistory(r.Context(), userID, conversationID, cursor, direction, limit)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			handler.WriteServiceError(w, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(messages)
+		json.NewEncoder(w).Encode(page)
 	})
 
-	// Apply simple authentication middleware (no Redis session check)
-	authMiddleware := middleware.AuthMiddleware(cfg.JWTSecret)
+	// Apply authentication middleware: JWT signature plus revocation list
+	authMiddleware := middleware.AuthMiddleware(verifier, revocations)
 	mux.Handle("/api/v1/messages/send", authMiddleware(sendMessageHandler))
 	mux.Handle("/api/v1/messages/history", authMiddleware(getHistoryHandler))
 
-	http.Handle("/api/", mux)
+	// Offline catch-up endpoint
+	messageHandler := handler.NewMessageHandler(chatService)
+	mux.Handle("/api/v1/sync", authMiddleware(http.HandlerFunc(messageHandler.Sync)))
+
+	// Delivery and read receipts
+	mux.Handle("/api/v1/receipts", authMiddleware(http.HandlerFunc(messageHandler.Receipts)))
+	mux.Handle("/api/v1/messages/readers", authMiddleware(http.HandlerFunc(messageHandler.Readers)))
+
+	// Message editing
+	mux.Handle("/api/v1/messages/edit", authMiddleware(http.HandlerFunc(messageHandler.Edit)))
+	mux.Handle("/api/v1/messages/revisions", authMiddleware(http.HandlerFunc(messageHandler.Revisions)))
+
+	// Message deletion
+	mux.Handle("/api/v1/messages", authMiddleware(http.HandlerFunc(messageHandler.Delete)))
+
+	// Reactions
+	mux.Handle("/api/v1/messages/reactions", authMiddleware(http.HandlerFunc(messageHandler.Reactions)))
+
+	// Quote replies and threads
+	mux.Handle("/api/v1/messages/reply", authMiddleware(http.HandlerFunc(messageHandler.Reply)))
+	mux.Handle("/api/v1/messages/thread", authMiddleware(http.HandlerFunc(messageHandler.Thread)))
+
+	// Full-text search
+	mux.Handle("/api/v1/messages/search", authMiddleware(http.HandlerFunc(messageHandler.Search)))
+
+	// Group chat endpoints
+	groupHandler := handler.NewGroupHandler(chatService)
+	mux.Handle("/api/v1/groups", authMiddleware(http.HandlerFunc(groupHandler.CreateGroup)))
+	mux.Handle("/api/v1/groups/members", authMiddleware(http.HandlerFunc(groupHandler.Members)))
+	mux.Handle("/api/v1/groups/members/role", authMiddleware(http.HandlerFunc(groupHandler.ChangeRole)))
+	mux.Handle("/api/v1/groups/leave", authMiddleware(http.HandlerFunc(groupHandler.Leave)))
+
+	// Conversation list (inbox)
+	conversationHandler := handler.NewConversationHandler(chatService)
+	mux.Handle("/api/v1/conversations", authMiddleware(http.HandlerFunc(conversationHandler.List)))
+	mux.Handle("/api/v1/conversations/settings", authMiddleware(http.HandlerFunc(conversationHandler.Settings)))
+
+	attachmentHandler := handler.NewAttachmentHandler(chatService, cfg.MaxAttachment)
+	mux.Handle("/api/v1/attachments", authMiddleware(http.HandlerFunc(attachmentHandler.Attachments)))
+
+	presenceHandler := handler.NewPresenceHandler(chatService)
+	mux.Handle("/api/v1/presence", authMiddleware(http.HandlerFunc(presenceHandler.Batch)))
+
+	public.Handle("/api/", mux)
+
+	// Metrics go on a separate listener, loopback-only unless configured
+	// otherwise
+	admin := http.NewServeMux()
+	admin.Handle("/debug/vars", expvar.Handler())
+	go func() {
+		if err := http.ListenAndServe(cfg.AdminAddr, admin); err != nil {
+			log.Printf("Admin listener stopped: %v", err)
+		}
+	}()
 
 	// Start server
 	log.Printf("Chat service starting on port %s", cfg.HTTPPort)
-	log.Println("WebSocket endpoint: /ws?token=<JWT_TOKEN>")
-	log.Println("Send message: POST /api/v1/messages/send")
-	log.Println("Get history: GET /api/v1/messages/history?conversation_id=<ID>")
+	log.Println("WebSocket endpoint: /ws?token=<JWT_TOKEN> (JSON envelopes, protocol v1)")
+	log.Println("Send message: POST /api/v1/messages/send (client_message_id makes retries safe)")
+	log.Println("Conversations: GET /api/v1/conversations?cursor=<CURSOR>, PUT /api/v1/conversations/settings")
+	log.Println("Get history: GET /api/v1/messages/history?conversation_id=<ID>&before=<CURSOR>|after=<CURSOR>&limit=<N>")
+	log.Println("Sync: GET /api/v1/sync?cursor=<CURSOR> (or a sync event on /ws)")
+	log.Println("Receipts: POST /api/v1/receipts, GET /api/v1/messages/readers?message_id=<ID>")
+	log.Println("Edit message: PUT /api/v1/messages/edit, GET /api/v1/messages/revisions?message_id=<ID>")
+	log.Println("Delete message: DELETE /api/v1/messages?message_id=<ID>&mode=me|everyone")
+	log.Println("Reactions: POST|DELETE /api/v1/messages/reactions")
+	log.Println("Replies: POST /api/v1/messages/reply, GET /api/v1/messages/thread?message_id=<ID>&before=<CURSOR>|after=<CURSOR>")
+	log.Println("Search: GET /api/v1/messages/search?q=<TEXT>&conversation_id=<ID>&sender_id=<ID>&from=<RFC3339>&to=<RFC3339>&cursor=<CURSOR>")
+	log.Println("Groups: POST /api/v1/groups, GET|POST|DELETE /api/v1/groups/members")
+	log.Println("Presence: GET /api/v1/presence?user_ids=<ID>,<ID>")
+	log.Println("Attachments: POST /api/v1/attachments (multipart file), GET /api/v1/attachments?id=<ID>&thumbnail=1")
 	log.Println("")
-	log.Println("⚠️  TESTING MODE: Redis session validation disabled")
-	log.Println("⚠️  Only JWT signature is validated")
+	log.Printf("Delivery bus: %s", cfg.DeliveryBus)
+	log.Printf("Attachment storage: %s", cfg.Storage)
+	log.Printf("Presence: %s", cfg.Presence)
+	log.Printf("User directory: %s (%s)", cfg.UserDirectory, cfg.UserGRPCAddr)
+	log.Printf("WebSocket metrics: GET /debug/vars on %s", cfg.AdminAddr)
+	log.Printf("Token revocation: %s (fail %s)", cfg.Revocation, cfg.RevocationFail)
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", cfg.HTTPPort), nil); err != nil {
+	if err := http.ListenAndServe(fmt.Sprintf(":%s", cfg.HTTPPort), public); err != nil {
 		log.Fatalln("Server failed:", err)
 	}
 }
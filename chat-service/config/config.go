@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	HTTPPort       string
+	AdminAddr      string
 	GRPCPort       string
 	DBHost         string
 	DBPort         string
@@ -18,15 +21,58 @@ type Config struct {
 	RedisHost      string
 	RedisPort      string
 	RedisDB        int
+	DeliveryBus    string
+	WSSendBuffer   int
+	WSOverflow     string
+	WSPingInterval time.Duration
+	WSPongWait     time.Duration
+	WSWriteWait    time.Duration
+	WSMaxMessage   int64
+	TypingTimeout  time.Duration
+	EditWindow     time.Duration
+	Reactions      []string
+	Storage        string
+	StoragePath    string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	MaxAttachment  int64
+	Presence       string
+	PresenceTTL    time.Duration
+	AwayAfter      time.Duration
+	PresenceSweep  time.Duration
 	UserServiceURL string
+	UserDirectory  string
+	UserGRPCAddr   string
+	UserTimeout    time.Duration
+	UserAttempts   int
+	UserToken      string
+	Revocation     string
+	RevocationFail string
+	RevokeRecheck  time.Duration
 	JWTSecret      string
+	JWTIssuer      string
+	JWTAudience    string
+	JWTKeys        string
+	JWKSURL        string
+	JWKSCacheTTL   time.Duration
+	// JWTAcceptHS256Until keeps HS256 tokens signed with JWTSecret valid
+	// next to the JWKS keys until then, see jwtauth.Config
+	JWTAcceptHS256Until time.Time
 }
 
 func Load() *Config {
 	redisDB, _ := strconv.Atoi(getEnv("REDIS_DB", "0"))
+	wsSendBuffer, _ := strconv.Atoi(getEnv("CHAT_WS_SEND_BUFFER", "256"))
+	wsMaxMessage, _ := strconv.ParseInt(getEnv("CHAT_WS_MAX_MESSAGE_SIZE", "65536"), 10, 64)
+	maxAttachment, _ := strconv.ParseInt(getEnv("CHAT_ATTACHMENT_MAX_SIZE", "26214400"), 10, 64)
+	userAttempts, _ := strconv.Atoi(getEnv("CHAT_USER_SERVICE_MAX_ATTEMPTS", "3"))
 
 	return &Config{
 		HTTPPort:       getEnv("HTTP_PORT", "8082"),
+		AdminAddr:      getEnv("CHAT_ADMIN_ADDR", "127.0.0.1:9093"),
 		GRPCPort:       getEnv("GRPC_PORT", "9092"),
 		DBHost:         getEnv("CHAT_DB_HOST", "localhost"),
 		DBPort:         getEnv("CHAT_DB_PORT", "5432"),
@@ -37,8 +83,45 @@ func Load() *Config {
 		RedisHost:      getEnv("REDIS_HOST", "localhost"),
 		RedisPort:      getEnv("REDIS_PORT", "6379"),
 		RedisDB:        redisDB,
+		DeliveryBus:    getEnv("CHAT_DELIVERY_BUS", "memory"),
+		WSSendBuffer:   wsSendBuffer,
+		WSOverflow:     getEnv("CHAT_WS_OVERFLOW_POLICY", "disconnect"),
+		WSPingInterval: getDuration("CHAT_WS_PING_INTERVAL", 30*time.Second),
+		WSPongWait:     getDuration("CHAT_WS_PONG_WAIT", 60*time.Second),
+		WSWriteWait:    getDuration("CHAT_WS_WRITE_WAIT", 10*time.Second),
+		WSMaxMessage:   wsMaxMessage,
+		TypingTimeout:  getDuration("CHAT_TYPING_TIMEOUT", 6*time.Second),
+		EditWindow:     getDuration("CHAT_EDIT_WINDOW", 15*time.Minute),
+		Reactions:      getList("CHAT_ALLOWED_REACTIONS"),
+		Storage:        getEnv("CHAT_STORAGE_BACKEND", "local"),
+		StoragePath:    getEnv("CHAT_STORAGE_PATH", "./data/attachments"),
+		S3Endpoint:     getEnv("CHAT_S3_ENDPOINT", "http://localhost:9000"),
+		S3Region:       getEnv("CHAT_S3_REGION", "us-east-1"),
+		S3Bucket:       getEnv("CHAT_S3_BUCKET", "chat-attachments"),
+		S3AccessKey:    getEnv("CHAT_S3_ACCESS_KEY", ""),
+		S3SecretKey:    getEnv("CHAT_S3_SECRET_KEY", ""),
+		MaxAttachment:  maxAttachment,
+		Presence:       getEnv("CHAT_PRESENCE", "redis"),
+		PresenceTTL:    getDuration("CHAT_PRESENCE_TTL", 60*time.Second),
+		AwayAfter:      getDuration("CHAT_PRESENCE_AWAY_AFTER", 5*time.Minute),
+		PresenceSweep:  getDuration("CHAT_PRESENCE_SWEEP_INTERVAL", 15*time.Second),
 		UserServiceURL: getEnv("USER_SERVICE_URL", "http://localhost:8081"),
+		UserDirectory:  getEnv("CHAT_USER_DIRECTORY", "grpc"),
+		UserGRPCAddr:   getEnv("USER_SERVICE_GRPC_ADDR", "localhost:9091"),
+		UserTimeout:    getDuration("CHAT_USER_SERVICE_TIMEOUT", 2*time.Second),
+		UserAttempts:   userAttempts,
+		UserToken:      getEnv("USER_SERVICE_GRPC_TOKEN", ""),
+		Revocation:     getEnv("CHAT_TOKEN_REVOCATION", "redis"),
+		RevocationFail: getEnv("CHAT_REVOCATION_FAIL_MODE", getEnv("REVOCATION_FAIL_MODE", "open")),
+		RevokeRecheck:  getDuration("CHAT_REVOCATION_RECHECK", time.Minute),
 		JWTSecret:      getEnv("JWT_SECRET", "your-super-secret-key"),
+		JWTIssuer:      getEnv("JWT_ISSUER", "user-service"),
+		JWTAudience:    getEnv("JWT_AUDIENCE", "chat-app"),
+		JWTKeys:        getEnv("CHAT_JWT_KEYS", "jwks"),
+		JWKSURL:        getEnv("JWT_JWKS_URL", "http://localhost:8081/.well-known/jwks.json"),
+		JWKSCacheTTL:   getDuration("CHAT_JWKS_CACHE_TTL", 5*time.Minute),
+
+		JWTAcceptHS256Until: getTime("JWT_ACCEPT_HS256_UNTIL"),
 	}
 }
 
@@ -66,3 +149,32 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getTime reads an RFC 3339 timestamp, the zero time if the variable is
+// unset or malformed
+func getTime(key string) time.Time {
+	value, err := time.Parse(time.RFC3339, os.Getenv(key))
+	if err != nil {
+		return time.Time{}
+	}
+	return value
+}
+
+// getList reads a comma separated list, nil if the variable is unset
+func getList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
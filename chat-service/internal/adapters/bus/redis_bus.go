@@ -0,0 +1,83 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+)
+
+// DefaultChannel is the Redis pub/sub channel delivery events travel on
+const DefaultChannel = "chat:deliveries"
+
+// deliveryEvent is what travels between replicas over Redis
+type deliveryEvent struct {
+	UserIDs []int64         `json:"user_ids"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// RedisBus fans delivery events out to every chat-service replica. Each
+// replica pushes the events it receives to the connections it holds
+// locally, so a sender on one replica reaches a recipient on another.
+type RedisBus struct {
+	client  *redis.Client
+	channel string
+	local   *websocket.ClientManager
+}
+
+func NewRedisBus(client *redis.Client, channel string, local *websocket.ClientManager) *RedisBus {
+	return &RedisBus{
+		client:  client,
+		channel: channel,
+		local:   local,
+	}
+}
+
+// Publish sends the event to all replicas. If Redis is unreachable the
+// event is still delivered to the connections held by this replica.
+func (b *RedisBus) Publish(ctx context.Context, userIDs []int64, payload []byte) error {
+	data, err := json.Marshal(deliveryEvent{UserIDs: userIDs, Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	if err := b.client.Publish(ctx, b.channel, data).Err(); err != nil {
+		b.local.Publish(ctx, userIDs, payload)
+		return err
+	}
+	return nil
+}
+
+// Run subscribes to the delivery channel and forwards events to the local
+// connections until ctx is cancelled
+func (b *RedisBus) Run(ctx context.Context) error {
+	sub := b.client.Subscribe(ctx, b.channel)
+	defer sub.Close()
+
+	// Wait for the subscription to be confirmed before consuming
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			var event deliveryEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("Dropping malformed delivery event: %v", err)
+				continue
+			}
+			b.local.Publish(ctx, event.UserIDs, event.Payload)
+		}
+	}
+}
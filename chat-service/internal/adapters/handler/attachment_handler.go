@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/service"
+	"github.com/zhanserikAmangeldi/chat-service/internal/middleware"
+)
+
+type AttachmentHandler struct {
+	service *service.ChatService
+	maxSize int64
+}
+
+// NewAttachmentHandler serves attachment uploads of at most maxSize bytes
+func NewAttachmentHandler(service *service.ChatService, maxSize int64) *AttachmentHandler {
+	return &AttachmentHandler{service: service, maxSize: maxSize}
+}
+
+// Attachments routes /api/v1/attachments by method
+func (h *AttachmentHandler) Attachments(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.upload(w, r)
+	case http.MethodGet:
+		h.download(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// upload handles POST /api/v1/attachments with a multipart "file" field
+func (h *AttachmentHandler) upload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	// Leave room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+1<<20)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteServiceError(w, service.ErrAttachmentTooLarge)
+			return
+		}
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.service.UploadAttachment(r.Context(), userID, header.Filename, header.Header.Get("Content-Type"), file)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, attachment)
+}
+
+// download handles GET /api/v1/attachments?id=<ID>&thumbnail=1
+func (h *AttachmentHandler) download(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	attachmentID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+	thumbnail := r.URL.Query().Get("thumbnail") == "1"
+
+	attachment, content, err := h.service.OpenAttachment(r.Context(), userID, attachmentID, thumbnail)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+	defer content.Close()
+
+	// Always serve as a download so uploaded HTML or SVG never runs in our
+	// origin
+	if thumbnail {
+		w.Header().Set("Content-Type", "image/jpeg")
+	} else {
+		w.Header().Set("Content-Type", attachment.MimeType)
+		w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("Failed to stream attachment %d: %v", attachment.ID, err)
+	}
+}
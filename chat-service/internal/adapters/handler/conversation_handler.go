@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/service"
+	"github.com/zhanserikAmangeldi/chat-service/internal/middleware"
+)
+
+type ConversationHandler struct {
+	service *service.ChatService
+}
+
+func NewConversationHandler(service *service.ChatService) *ConversationHandler {
+	return &ConversationHandler{service: service}
+}
+
+// List handles GET /api/v1/conversations?cursor=<CURSOR>&limit=<N>
+func (h *ConversationHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	page, err := h.service.ListConversations(r.Context(), userID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// Settings handles PUT /api/v1/conversations/settings
+func (h *ConversationHandler) Settings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	// muted_until omitted or null unmutes the conversation
+	var req struct {
+		ConversationID int64      `json:"conversation_id"`
+		Pinned         bool       `json:"pinned"`
+		MutedUntil     *time.Time `json:"muted_until"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateConversationSettings(r.Context(), userID, req.ConversationID, req.Pinned, req.MutedUntil); err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
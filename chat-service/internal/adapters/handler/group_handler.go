@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/service"
+	"github.com/zhanserikAmangeldi/chat-service/internal/middleware"
+)
+
+type GroupHandler struct {
+	service *service.ChatService
+}
+
+func NewGroupHandler(service *service.ChatService) *GroupHandler {
+	return &GroupHandler{service: service}
+}
+
+// CreateGroup handles POST /api/v1/groups
+func (h *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Title     string  `json:"title"`
+		MemberIDs []int64 `json:"member_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	conv, err := h.service.CreateGroup(r.Context(), userID, req.Title, req.MemberIDs)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, conv)
+}
+
+// Members handles /api/v1/groups/members:
+// GET lists members, POST adds members, DELETE removes one member
+func (h *GroupHandler) Members(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		conversationID, ok := queryInt64(r, "conversation_id")
+		if !ok {
+			http.Error(w, "conversation_id required", http.StatusBadRequest)
+			return
+		}
+
+		members, err := h.service.GetMembers(r.Context(), userID, conversationID)
+		if err != nil {
+			WriteServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, members)
+
+	case http.MethodPost:
+		var req struct {
+			ConversationID int64   `json:"conversation_id"`
+			UserIDs        []int64 `json:"user_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		added, err := h.service.AddMembers(r.Context(), userID, req.ConversationID, req.UserIDs)
+		if err != nil {
+			WriteServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, added)
+
+	case http.MethodDelete:
+		conversationID, ok := queryInt64(r, "conversation_id")
+		if !ok {
+			http.Error(w, "conversation_id required", http.StatusBadRequest)
+			return
+		}
+		memberID, ok := queryInt64(r, "user_id")
+		if !ok {
+			http.Error(w, "user_id required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.RemoveMember(r.Context(), userID, conversationID, memberID); err != nil {
+			WriteServiceError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ChangeRole handles PUT /api/v1/groups/members/role
+func (h *GroupHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ConversationID int64  `json:"conversation_id"`
+		UserID         int64  `json:"user_id"`
+		Role           string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ChangeRole(r.Context(), userID, req.ConversationID, req.UserID, req.Role); err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Leave handles POST /api/v1/groups/leave
+func (h *GroupHandler) Leave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ConversationID int64 `json:"conversation_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.LeaveGroup(r.Context(), userID, req.ConversationID); err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
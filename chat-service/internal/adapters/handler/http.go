@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/service"
+)
+
+// writeJSON encodes body as the JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// WriteServiceError maps chat service errors to HTTP status codes
+func WriteServiceError(w http.ResponseWriter, err error) {
+	var forbidden *service.ForbiddenError
+	switch {
+	case errors.As(err, &forbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrConversationNotFound),
+		errors.Is(err, service.ErrMessageNotFound),
+		errors.Is(err, service.ErrAttachmentNotFound),
+		errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrNotParticipant),
+		errors.Is(err, service.ErrPermissionDenied),
+		errors.Is(err, service.ErrEditWindowExpired):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrNotGroupConversation),
+		errors.Is(err, service.ErrInvalidRole),
+		errors.Is(err, service.ErrEmptyTitle),
+		errors.Is(err, service.ErrInvalidReceipt),
+		errors.Is(err, service.ErrInvalidDirection),
+		errors.Is(err, service.ErrEmptyContent),
+		errors.Is(err, service.ErrInvalidDeleteMode),
+		errors.Is(err, service.ErrReactionNotAllowed),
+		errors.Is(err, service.ErrInvalidAttachment),
+		errors.Is(err, service.ErrEmptySearch),
+		errors.Is(err, service.ErrInvalidDateRange),
+		errors.Is(err, service.ErrInvalidClientMessageID),
+		errors.Is(err, service.ErrTooManyUsers),
+		errors.Is(err, domain.ErrInvalidCursor):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrAttachmentTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, service.ErrStorageUnavailable),
+		errors.Is(err, service.ErrPresenceUnavailable),
+		errors.Is(err, service.ErrUserLookupFailed):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ErrCursorConflict is returned by PageParams when both cursors are given
+var ErrCursorConflict = errors.New("only one of before and after may be set")
+
+// PageParams reads the paging parameters of history-like endpoints:
+// before walks towards older messages, after towards newer ones; without
+// either, direction picks the end to start from
+func PageParams(r *http.Request) (cursor string, direction domain.Direction, limit int, err error) {
+	query := r.URL.Query()
+	before, after := query.Get("before"), query.Get("after")
+	direction = domain.Direction(query.Get("direction"))
+	switch {
+	case before != "" && after != "":
+		return "", "", 0, ErrCursorConflict
+	case before != "":
+		cursor, direction = before, domain.DirectionBackward
+	case after != "":
+		cursor, direction = after, domain.DirectionForward
+	}
+	limit, _ = strconv.Atoi(query.Get("limit"))
+	return cursor, direction, limit, nil
+}
+
+// queryInt64 reads a required int64 query parameter
+func queryInt64(r *http.Request, name string) (int64, bool) {
+	value, err := strconv.ParseInt(r.URL.Query().Get(name), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// optionalInt64 reads an optional int64 query parameter, nil when absent
+func optionalInt64(r *http.Request, name string) (*int64, error) {
+	if r.URL.Query().Get(name) == "" {
+		return nil, nil
+	}
+	value, ok := queryInt64(r, name)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &value, nil
+}
+
+// optionalTime reads an optional RFC 3339 query parameter, nil when absent
+func optionalTime(r *http.Request, name string) (*time.Time, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, expected an RFC 3339 time", name)
+	}
+	return &value, nil
+}
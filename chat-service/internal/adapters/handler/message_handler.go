@@ -0,0 +1,354 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/service"
+	"github.com/zhanserikAmangeldi/chat-service/internal/middleware"
+)
+
+type MessageHandler struct {
+	service *service.ChatService
+}
+
+func NewMessageHandler(service *service.ChatService) *MessageHandler {
+	return &MessageHandler{service: service}
+}
+
+// Sync handles GET /api/v1/sync?cursor=<CURSOR>&limit=<N>
+func (h *MessageHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	result, err := h.service.Sync(r.Context(), userID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Receipts handles POST /api/v1/receipts
+func (h *MessageHandler) Receipts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ConversationID int64  `json:"conversation_id"`
+		Seq            int64  `json:"seq"`
+		Type           string `json:"type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := h.service.UpdateReceipt(r.Context(), userID, req.ConversationID, req.Seq, req.Type)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, receipt)
+}
+
+// Readers handles GET /api/v1/messages/readers?message_id=<ID>
+func (h *MessageHandler) Readers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	messageID, ok := queryInt64(r, "message_id")
+	if !ok {
+		http.Error(w, "message_id required", http.StatusBadRequest)
+		return
+	}
+
+	readers, err := h.service.GetMessageReaders(r.Context(), userID, messageID)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, readers)
+}
+
+// Edit handles PUT /api/v1/messages/edit
+func (h *MessageHandler) Edit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		MessageID int64  `json:"message_id"`
+		Content   string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := h.service.EditMessage(r.Context(), userID, req.MessageID, req.Content)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, msg)
+}
+
+// Revisions handles GET /api/v1/messages/revisions?message_id=<ID>
+func (h *MessageHandler) Revisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	messageID, ok := queryInt64(r, "message_id")
+	if !ok {
+		http.Error(w, "message_id required", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.service.GetMessageRevisions(r.Context(), userID, messageID)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, revisions)
+}
+
+// Delete handles DELETE /api/v1/messages?message_id=<ID>&mode=me|everyone,
+// mode defaults to me
+func (h *MessageHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	messageID, ok := queryInt64(r, "message_id")
+	if !ok {
+		http.Error(w, "message_id required", http.StatusBadRequest)
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = domain.DeleteForMe
+	}
+
+	deletion, err := h.service.DeleteMessage(r.Context(), userID, messageID, mode)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deletion)
+}
+
+// Reactions handles /api/v1/messages/reactions:
+// POST adds a reaction, DELETE ?message_id=<ID>&emoji=<EMOJI> removes it
+func (h *MessageHandler) Reactions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var req struct {
+			MessageID int64  `json:"message_id"`
+			Emoji     string `json:"emoji"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		reaction, err := h.service.AddReaction(r.Context(), userID, req.MessageID, req.Emoji)
+		if err != nil {
+			WriteServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, reaction)
+
+	case http.MethodDelete:
+		messageID, ok := queryInt64(r, "message_id")
+		if !ok {
+			http.Error(w, "message_id required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.RemoveReaction(r.Context(), userID, messageID, r.URL.Query().Get("emoji")); err != nil {
+			WriteServiceError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Reply handles POST /api/v1/messages/reply. thread=true posts in the
+// parent's thread, otherwise the parent is quoted inline. client_message_id
+// makes retries safe like for /api/v1/messages/send.
+func (h *MessageHandler) Reply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		MessageID       int64  `json:"message_id"`
+		Content         string `json:"content"`
+		Thread          bool   `json:"thread"`
+		ClientMessageID string `json:"client_message_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := h.service.Reply(r.Context(), userID, req.MessageID, req.Content, req.ClientMessageID, req.Thread)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, msg)
+}
+
+// Thread handles GET /api/v1/messages/thread?message_id=<ROOT>&before=<CURSOR>|after=<CURSOR>&limit=<N>
+func (h *MessageHandler) Thread(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	rootID, ok := queryInt64(r, "message_id")
+	if !ok {
+		http.Error(w, "message_id required", http.StatusBadRequest)
+		return
+	}
+
+	cursor, direction, limit, err := PageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.service.GetThread(r.Context(), userID, rootID, cursor, direction, limit)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// Search handles GET /api/v1/messages/search?q=<TEXT>&conversation_id=<ID>&sender_id=<ID>&from=<RFC3339>&to=<RFC3339>&cursor=<CURSOR>&limit=<N>
+func (h *MessageHandler) Search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	params := r.URL.Query()
+	query := domain.SearchQuery{Text: params.Get("q")}
+	query.Limit, _ = strconv.Atoi(params.Get("limit"))
+
+	var err error
+	if query.ConversationID, err = optionalInt64(r, "conversation_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if query.SenderID, err = optionalInt64(r, "sender_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if query.From, err = optionalTime(r, "from"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if query.To, err = optionalTime(r, "to"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.service.SearchMessages(r.Context(), userID, query, params.Get("cursor"))
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/service"
+	"github.com/zhanserikAmangeldi/chat-service/internal/middleware"
+)
+
+type PresenceHandler struct {
+	service *service.ChatService
+}
+
+func NewPresenceHandler(service *service.ChatService) *PresenceHandler {
+	return &PresenceHandler{service: service}
+}
+
+// Batch handles GET /api/v1/presence?user_ids=<ID>,<ID>,...
+func (h *PresenceHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var userIDs []int64
+	for _, raw := range strings.Split(r.URL.Query().Get("user_ids"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid user_ids", http.StatusBadRequest)
+			return
+		}
+		userIDs = append(userIDs, id)
+	}
+	if len(userIDs) == 0 {
+		http.Error(w, "user_ids required", http.StatusBadRequest)
+		return
+	}
+
+	presence, err := h.service.GetPresence(r.Context(), userID, userIDs)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"presence": presence})
+}
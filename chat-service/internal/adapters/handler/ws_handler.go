@@ -1,14 +1,21 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	ws "github.com/gorilla/websocket"
 	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/service"
+	"github.com/zhanserikAmangeldi/chat-service/internal/middleware"
+	"github.com/zhanserikAmangeldi/jwtauth"
+	"github.com/zhanserikAmangeldi/jwtauth/httpauth"
 )
 
 var upgrader = ws.Upgrader{
@@ -20,15 +27,35 @@ var upgrader = ws.Upgrader{
 	},
 }
 
+// defaultRevocationRecheck is how often open connections re-check their
+// token, in case a revocation announcement was missed
+const defaultRevocationRecheck = time.Minute
+
 type WSHandler struct {
-	manager   *websocket.ClientManager
-	jwtSecret string
+	manager  *websocket.ClientManager
+	service  *service.ChatService
+	verifier *jwtauth.Verifier
+
+	revocations *middleware.RevocationList
+	recheck     time.Duration
 }
 
-func NewWSHandler(manager *websocket.ClientManager, jwtSecret string) *WSHandler {
+func NewWSHandler(manager *websocket.ClientManager, service *service.ChatService, verifier *jwtauth.Verifier) *WSHandler {
 	return &WSHandler{
-		manager:   manager,
-		jwtSecret: jwtSecret,
+		manager:  manager,
+		service:  service,
+		verifier: verifier,
+		recheck:  defaultRevocationRecheck,
+	}
+}
+
+// SetRevocations rejects revoked tokens at connect time and closes open
+// connections once their token is revoked. Tokens are re-checked every
+// recheck on top of the revocation announcements.
+func (h *WSHandler) SetRevocations(revocations *middleware.RevocationList, recheck time.Duration) {
+	h.revocations = revocations
+	if recheck > 0 {
+		h.recheck = recheck
 	}
 }
 
@@ -38,62 +65,229 @@ func (h *WSHandler) HandleConnection(w http.ResponseWriter, r *http.Request) {
 	tokenString := r.URL.Query().Get("token")
 	if tokenString == "" {
 		// Try Authorization header as fallback
-		authHeader := r.Header.Get("Authorization")
-		tokenString = strings.TrimPrefix(authHeader, "Bearer ")
+		var err error
+		if tokenString, err = httpauth.BearerToken(r); err != nil {
+			http.Error(w, "Missing authentication token", http.StatusUnauthorized)
+			return
+		}
 	}
 
-	if tokenString == "" {
-		http.Error(w, "Missing authentication token", http.StatusUnauthorized)
+	// Validate the access token and reject tokens revoked by logout
+	claims, err := h.verifier.Authenticate(r.Context(), tokenString, h.revocations.Checks()...)
+	if err != nil {
+		http.Error(w, err.Error(), httpauth.Status(err))
 		return
 	}
+	userID := claims.UserID
+
+	// Upgrade the connection
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("Failed to upgrade connection:", err)
+		return
+	}
+
+	// Register the authenticated client, each device gets its own connection id
+	client := h.manager.AddClient(userID, conn)
+	log.Printf("User %d connected via WebSocket (connection %s)", userID, client.ID)
+
+	// Presence follows the connection: online now, kept alive by heartbeats
+	// while the socket is open, dropped when it closes
+	h.service.Connected(r.Context(), userID, client.ID)
+	stopHeartbeats := make(chan struct{})
+	go h.heartbeat(userID, client.ID, stopHeartbeats)
 
-	// Validate JWT token
-	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	// Logging out ends the connection too
+	revoked, stopWatching := h.revocations.Watch(tokenString)
+	go h.watchToken(client, tokenString, revoked, stopHeartbeats)
+
+	// Listen for messages until the peer disconnects or stops answering pings
+	defer h.manager.RemoveClient(client)
+	err = client.ReadPump(func(data []byte) {
+		if reply := h.handleEvent(r.Context(), userID, client.ID, data); reply != nil {
+			h.manager.SendToClient(client, reply)
 		}
-		return []byte(h.jwtSecret), nil
 	})
+	log.Printf("User %d disconnected (connection %s): %v", userID, client.ID, err)
+
+	close(stopHeartbeats)
+	stopWatching()
+	h.service.Disconnected(context.Background(), userID, client.ID)
+}
 
-	if err != nil || !token.Valid {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+// watchToken closes client once token is revoked, either announced on
+// revoked or found by the periodic re-check. Only a confirmed revocation
+// closes the connection: when Redis is down the connection stays open.
+func (h *WSHandler) watchToken(client *websocket.Client, token string, revoked <-chan struct{}, stop <-chan struct{}) {
+	if h.revocations == nil {
 		return
 	}
 
-	// Extract claims
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+	ticker := time.NewTicker(h.recheck)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-revoked:
+		case <-ticker.C:
+			if err := h.revocations.Check(context.Background(), token); !errors.Is(err, jwtauth.ErrTokenRevoked) {
+				continue
+			}
+		}
+
+		log.Printf("Closing user %d (connection %s): token revoked", client.UserID, client.ID)
+		client.CloseWithReason(websocket.CloseTokenRevoked, "token revoked")
 		return
 	}
+}
 
-	// Get user_id from claims
-	userIDFloat, ok := claims["user_id"].(float64)
-	if !ok {
-		http.Error(w, "Invalid user_id in token", http.StatusUnauthorized)
-		return
+// heartbeat refreshes the presence of an open connection until stop is
+// closed, three times per TTL so one lost beat does not flip it offline
+func (h *WSHandler) heartbeat(userID int64, connID string, stop <-chan struct{}) {
+	ticker := time.NewTicker(h.service.PresenceTTL() / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			h.service.Heartbeat(context.Background(), userID, connID, false)
+		}
 	}
-	userID := int64(userIDFloat)
+}
 
-	// Upgrade the connection
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Println("Failed to upgrade connection:", err)
-		return
+// handleEvent dispatches a single inbound frame and returns the frame to
+// send back to the client, if any
+func (h *WSHandler) handleEvent(ctx context.Context, userID int64, connID string, data []byte) []byte {
+	var env websocket.Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return websocket.NewErrorEnvelope("", websocket.ErrCodeBadRequest, "invalid envelope")
 	}
 
-	// Register the authenticated client
-	h.manager.AddClient(userID, conn)
-	log.Printf("User %d connected via WebSocket", userID)
+	if env.Version != websocket.ProtocolVersion {
+		return websocket.NewErrorEnvelope(env.ID, websocket.ErrCodeUnsupportedVersion,
+			fmt.Sprintf("protocol version %d is not supported", env.Version))
+	}
 
-	// Keep connection alive and listen for messages
-	defer h.manager.RemoveClient(userID)
-	for {
-		_, _, err := conn.ReadMessage()
+	// Anything but a keepalive ping is the user doing something
+	h.service.Heartbeat(ctx, userID, connID, env.Type != websocket.EventPing)
+
+	switch env.Type {
+	case websocket.EventPing:
+		reply, _ := websocket.NewEnvelope(websocket.EventPong, env.ID, nil)
+		return reply
+
+	case websocket.EventSendMessage:
+		var payload websocket.SendMessagePayload
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			return websocket.NewErrorEnvelope(env.ID, websocket.ErrCodeBadRequest, "invalid send_message payload")
+		}
+
+		var msg *domain.Message
+		var err error
+		switch {
+		case payload.ReplyToID != 0:
+			msg, err = h.service.Reply(ctx, userID, payload.ReplyToID, payload.Content, payload.ClientMessageID, payload.Thread, payload.AttachmentIDs...)
+		case payload.ConversationID != 0:
+			msg, err = h.service.SendGroupMessage(ctx, userID, payload.ConversationID, payload.Content, payload.ClientMessageID, payload.AttachmentIDs...)
+		default:
+			msg, err = h.service.SendMessage(ctx, userID, payload.RecipientID, payload.Content, payload.ClientMessageID, payload.AttachmentIDs...)
+		}
+		if err != nil {
+			return errorEnvelope(env.ID, err)
+		}
+
+		reply, _ := websocket.NewEnvelope(websocket.EventAck, env.ID, msg)
+		return reply
+
+	case websocket.EventTyping:
+		var payload websocket.TypingPayload
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			return websocket.NewErrorEnvelope(env.ID, websocket.ErrCodeBadRequest, "invalid typing payload")
+		}
+
+		if err := h.service.NotifyTyping(ctx, userID, payload.ConversationID, payload.Typing); err != nil {
+			return errorEnvelope(env.ID, err)
+		}
+		return nil
+
+	case websocket.EventSync:
+		var payload websocket.SyncPayload
+		if len(env.Payload) > 0 {
+			if err := json.Unmarshal(env.Payload, &payload); err != nil {
+				return websocket.NewErrorEnvelope(env.ID, websocket.ErrCodeBadRequest, "invalid sync payload")
+			}
+		}
+
+		result, err := h.service.Sync(ctx, userID, payload.Cursor, payload.Limit)
+		if err != nil {
+			return errorEnvelope(env.ID, err)
+		}
+
+		reply, _ := websocket.NewEnvelope(websocket.EventSyncResult, env.ID, result)
+		return reply
+
+	case websocket.EventMarkRead, websocket.EventMarkDelivered:
+		var payload websocket.MarkReadPayload
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			return websocket.NewErrorEnvelope(env.ID, websocket.ErrCodeBadRequest, "invalid "+env.Type+" payload")
+		}
+
+		kind := domain.ReceiptRead
+		if env.Type == websocket.EventMarkDelivered {
+			kind = domain.ReceiptDelivered
+		}
+
+		receipt, err := h.service.UpdateReceipt(ctx, userID, payload.ConversationID, payload.Seq, kind)
 		if err != nil {
-			log.Printf("User %d disconnected: %v", userID, err)
-			break
+			return errorEnvelope(env.ID, err)
 		}
-		// Handle incoming messages if needed
+
+		reply, _ := websocket.NewEnvelope(websocket.EventAck, env.ID, receipt)
+		return reply
+
+	default:
+		return websocket.NewErrorEnvelope(env.ID, websocket.ErrCodeUnknownEvent,
+			fmt.Sprintf("unknown event type %q", env.Type))
+	}
+}
+
+// errorEnvelope maps chat service errors to protocol error codes
+func errorEnvelope(id string, err error) []byte {
+	var forbidden *service.ForbiddenError
+	switch {
+	case errors.As(err, &forbidden):
+		return websocket.NewErrorEnvelope(id, websocket.ErrCodeForbidden, err.Error())
+	case errors.Is(err, service.ErrConversationNotFound),
+		errors.Is(err, service.ErrMessageNotFound),
+		errors.Is(err, service.ErrAttachmentNotFound),
+		errors.Is(err, service.ErrUserNotFound):
+		return websocket.NewErrorEnvelope(id, websocket.ErrCodeNotFound, err.Error())
+	case errors.Is(err, service.ErrNotParticipant),
+		errors.Is(err, service.ErrPermissionDenied),
+		errors.Is(err, service.ErrEditWindowExpired):
+		return websocket.NewErrorEnvelope(id, websocket.ErrCodeForbidden, err.Error())
+	case errors.Is(err, service.ErrNotGroupConversation),
+		errors.Is(err, service.ErrInvalidRole),
+		errors.Is(err, service.ErrEmptyTitle),
+		errors.Is(err, service.ErrInvalidReceipt),
+		errors.Is(err, service.ErrInvalidDirection),
+		errors.Is(err, service.ErrEmptyContent),
+		errors.Is(err, service.ErrInvalidDeleteMode),
+		errors.Is(err, service.ErrReactionNotAllowed),
+		errors.Is(err, service.ErrInvalidAttachment),
+		errors.Is(err, service.ErrEmptySearch),
+		errors.Is(err, service.ErrInvalidDateRange),
+		errors.Is(err, service.ErrInvalidClientMessageID),
+		errors.Is(err, domain.ErrInvalidCursor):
+		return websocket.NewErrorEnvelope(id, websocket.ErrCodeBadRequest, err.Error())
+	case errors.Is(err, service.ErrUserLookupFailed):
+		return websocket.NewErrorEnvelope(id, websocket.ErrCodeUnavailable, err.Error())
+	default:
+		log.Printf("WebSocket event failed: %v", err)
+		return websocket.NewErrorEnvelope(id, websocket.ErrCodeInternal, "internal error")
 	}
 }
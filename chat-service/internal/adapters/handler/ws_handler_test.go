@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	ws "github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/service"
+	"github.com/zhanserikAmangeldi/chat-service/internal/middleware"
+	"github.com/zhanserikAmangeldi/jwtauth"
+)
+
+// watchedConnection opens a socket whose server side is watched for the
+// revocation of token, and returns the client side of it
+func watchedConnection(t *testing.T, h *WSHandler, token string) *ws.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := h.manager.AddClient(7, conn)
+		defer h.manager.RemoveClient(client)
+
+		stop := make(chan struct{})
+		defer close(stop)
+		revoked, stopWatching := h.revocations.Watch(token)
+		defer stopWatching()
+		go h.watchToken(client, token, revoked, stop)
+
+		client.ReadPump(func([]byte) {})
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func newRevocationHandler(t *testing.T, recheck time.Duration) (*WSHandler, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	h := NewWSHandler(websocket.NewClientManager(), nil, nil)
+	h.SetRevocations(middleware.NewRevocationList(client, false), recheck)
+	return h, server
+}
+
+// expectClosed reads from conn until the server closes it and returns the
+// close code
+func expectClosed(t *testing.T, conn *ws.Conn) int {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			closeErr, ok := err.(*ws.CloseError)
+			require.True(t, ok, "expected a close frame, got %v", err)
+			return closeErr.Code
+		}
+	}
+}
+
+func TestWatchToken_AnnouncedRevocationClosesSocket(t *testing.T) {
+	h, server := newRevocationHandler(t, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.revocations.Run(ctx)
+	require.Eventually(t, func() bool {
+		return len(server.PubSubChannels(jwtauth.RevokedChannel)) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	conn := watchedConnection(t, h, "old-token")
+	server.Publish(jwtauth.RevokedChannel, "old-token")
+
+	assert.Equal(t, websocket.CloseTokenRevoked, expectClosed(t, conn))
+}
+
+func TestWatchToken_RecheckClosesSocket(t *testing.T) {
+	h, server := newRevocationHandler(t, 20*time.Millisecond)
+
+	// No announcement, e.g. it was missed while Redis was unreachable
+	conn := watchedConnection(t, h, "old-token")
+	require.NoError(t, server.Set(jwtauth.RevokedKey("old-token"), "1"))
+
+	assert.Equal(t, websocket.CloseTokenRevoked, expectClosed(t, conn))
+}
+
+func TestWatchToken_RedisDownKeepsSocketOpen(t *testing.T) {
+	h, server := newRevocationHandler(t, 20*time.Millisecond)
+
+	conn := watchedConnection(t, h, "token")
+	server.Close()
+
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, _, err := conn.ReadMessage()
+	var netErr interface{ Timeout() bool }
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout(), "expected the socket to stay open, got %v", err)
+}
+
+// replyRepo holds message 100 of group 10, which user 2 belongs to, and
+// the attachments user 2 uploaded. Anything else the service asks for
+// panics on the nil embedded repository.
+type replyRepo struct {
+	ports.ChatRepository
+	attachments []domain.Attachment
+	linked      []int64
+}
+
+func (r *replyRepo) WithinTransaction(ctx context.Context, fn func(repo ports.ChatRepository) error) error {
+	return fn(r)
+}
+
+func (r *replyRepo) GetMessageByID(ctx context.Context, id int64) (*domain.Message, error) {
+	if id != 100 {
+		return nil, nil
+	}
+	return &domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil
+}
+
+func (r *replyRepo) GetParticipant(ctx context.Context, conversationID, userID int64) (*domain.Participant, error) {
+	return &domain.Participant{ConversationID: conversationID, UserID: userID, Role: domain.RoleMember}, nil
+}
+
+func (r *replyRepo) GetParticipants(ctx context.Context, conversationID int64) ([]domain.Participant, error) {
+	return []domain.Participant{{UserID: 1}, {UserID: 2}}, nil
+}
+
+func (r *replyRepo) GetAttachments(ctx context.Context, ids []int64) ([]domain.Attachment, error) {
+	var found []domain.Attachment
+	for _, a := range r.attachments {
+		for _, id := range ids {
+			if a.ID == id {
+				found = append(found, a)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (r *replyRepo) SaveMessage(ctx context.Context, msg *domain.Message) error {
+	msg.ID = 101
+	return nil
+}
+
+func (r *replyRepo) LinkAttachments(ctx context.Context, messageID, conversationID, uploaderID int64, ids []int64) (int64, error) {
+	r.linked = append(r.linked, ids...)
+	return int64(len(ids)), nil
+}
+
+type discardBus struct{}
+
+func (discardBus) Publish(ctx context.Context, userIDs []int64, payload []byte) error { return nil }
+
+func sendReply(t *testing.T, h *WSHandler, attachmentIDs ...int64) websocket.Envelope {
+	t.Helper()
+
+	payload, err := json.Marshal(websocket.SendMessagePayload{ReplyToID: 100, AttachmentIDs: attachmentIDs})
+	require.NoError(t, err)
+	frame, err := json.Marshal(websocket.Envelope{Version: websocket.ProtocolVersion, Type: websocket.EventSendMessage, ID: "1", Payload: payload})
+	require.NoError(t, err)
+
+	var reply websocket.Envelope
+	require.NoError(t, json.Unmarshal(h.handleEvent(context.Background(), 2, "conn", frame), &reply))
+	return reply
+}
+
+func TestHandleEvent_ReplyCarriesAttachments(t *testing.T) {
+	repo := &replyRepo{attachments: []domain.Attachment{{ID: 7, UploaderID: 2}}}
+	h := NewWSHandler(websocket.NewClientManager(), service.NewChatService(repo, discardBus{}), nil)
+
+	reply := sendReply(t, h, 7)
+
+	require.Equal(t, websocket.EventAck, reply.Type, string(reply.Payload))
+	var msg domain.Message
+	require.NoError(t, json.Unmarshal(reply.Payload, &msg))
+	assert.Equal(t, int64(100), *msg.ReplyToID)
+	require.Len(t, msg.Attachments, 1)
+	assert.Equal(t, int64(7), msg.Attachments[0].ID)
+	assert.Equal(t, []int64{7}, repo.linked)
+}
+
+func TestHandleEvent_ReplyRejectsSomeoneElsesAttachment(t *testing.T) {
+	repo := &replyRepo{attachments: []domain.Attachment{{ID: 7, UploaderID: 1}}}
+	h := NewWSHandler(websocket.NewClientManager(), service.NewChatService(repo, discardBus{}), nil)
+
+	reply := sendReply(t, h, 7)
+
+	require.Equal(t, websocket.EventError, reply.Type)
+	var payload websocket.ErrorPayload
+	require.NoError(t, json.Unmarshal(reply.Payload, &payload))
+	assert.Equal(t, websocket.ErrCodeBadRequest, payload.Code)
+	assert.Empty(t, repo.linked)
+}
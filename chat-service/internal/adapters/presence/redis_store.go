@@ -0,0 +1,187 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+// ChangesStream is the Redis stream status changes are appended to. The
+// user service reads it in a consumer group and persists them, catching up
+// on whatever was appended while it was down.
+const ChangesStream = "presence:changes"
+
+// changesMaxLen bounds the stream; the user service only falls that far
+// behind when it has been down for a long time
+const changesMaxLen = 100000
+
+// maxStatusAttempts is how often UpdateStatus retries when the user's keys
+// change under it
+const maxStatusAttempts = 10
+
+// activityRetention keeps last activity around long after a user went
+// offline, for last seen
+const activityRetention = 30 * 24 * time.Hour
+
+// Key layout, per user:
+//
+//	presence:conns:<id>   sorted set of connection ids scored by expiry (unix ms)
+//	presence:active:<id>  last activity (unix ms)
+//	presence:status:<id>  last announced status
+//
+// plus presence:announced, the set of users announced as not offline.
+const announcedKey = "presence:announced"
+
+func connsKey(userID int64) string  { return "presence:conns:" + strconv.FormatInt(userID, 10) }
+func activeKey(userID int64) string { return "presence:active:" + strconv.FormatInt(userID, 10) }
+func statusKey(userID int64) string { return "presence:status:" + strconv.FormatInt(userID, 10) }
+
+// RedisStore shares presence between chat-service instances. A connection
+// counts as alive until its last heartbeat expires, so connections held by
+// a crashed instance fade out on their own.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Heartbeat(ctx context.Context, userID int64, connID string, ttl time.Duration, active bool) error {
+	now := time.Now()
+	key := connsKey(userID)
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Add(ttl).UnixMilli()), Member: connID})
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(now.UnixMilli(), 10))
+		pipe.PExpire(ctx, key, ttl)
+		if active {
+			pipe.Set(ctx, activeKey(userID), now.UnixMilli(), activityRetention)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) Disconnect(ctx context.Context, userID int64, connID string) error {
+	return s.client.ZRem(ctx, connsKey(userID), connID).Err()
+}
+
+func (s *RedisStore) States(ctx context.Context, userIDs []int64) (map[int64]ports.PresenceState, error) {
+	if len(userIDs) == 0 {
+		return map[int64]ports.PresenceState{}, nil
+	}
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	counts := make([]*redis.IntCmd, len(userIDs))
+	actives := make([]*redis.StringCmd, len(userIDs))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, userID := range userIDs {
+			counts[i] = pipe.ZCount(ctx, connsKey(userID), now, "+inf")
+			actives[i] = pipe.Get(ctx, activeKey(userID))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	states := make(map[int64]ports.PresenceState, len(userIDs))
+	for i, userID := range userIDs {
+		state := ports.PresenceState{Connected: counts[i].Val() > 0}
+		if ms, err := actives[i].Int64(); err == nil {
+			state.LastActiveAt = time.UnixMilli(ms)
+		}
+		states[userID] = state
+	}
+	return states, nil
+}
+
+func (s *RedisStore) UpdateStatus(ctx context.Context, userID int64, derive func(ports.PresenceState) domain.PresenceStatus) (ports.PresenceState, domain.PresenceStatus, error) {
+	var state ports.PresenceState
+	var previous domain.PresenceStatus
+
+	// Optimistic transaction: if a heartbeat, disconnect or another instance
+	// touches the user's keys between the read and the write, start over
+	update := func(tx *redis.Tx) error {
+		now := time.Now()
+		count, err := tx.ZCount(ctx, connsKey(userID), strconv.FormatInt(now.UnixMilli(), 10), "+inf").Result()
+		if err != nil {
+			return err
+		}
+		state = ports.PresenceState{Connected: count > 0}
+		if ms, err := tx.Get(ctx, activeKey(userID)).Int64(); err == nil {
+			state.LastActiveAt = time.UnixMilli(ms)
+		} else if !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		announced, err := tx.Get(ctx, statusKey(userID)).Result()
+		if errors.Is(err, redis.Nil) {
+			announced, err = string(domain.PresenceOffline), nil
+		}
+		if err != nil {
+			return err
+		}
+		previous = domain.PresenceStatus(announced)
+
+		status := derive(state)
+		id := strconv.FormatInt(userID, 10)
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, statusKey(userID), string(status), 0)
+			if status == domain.PresenceOffline {
+				pipe.SRem(ctx, announcedKey, id)
+			} else {
+				pipe.SAdd(ctx, announcedKey, id)
+			}
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < maxStatusAttempts; attempt++ {
+		err := s.client.Watch(ctx, update, connsKey(userID), activeKey(userID), statusKey(userID))
+		if !errors.Is(err, redis.TxFailedErr) {
+			return state, previous, err
+		}
+	}
+	return ports.PresenceState{}, "", fmt.Errorf("presence of user %d kept changing: %w", userID, redis.TxFailedErr)
+}
+
+func (s *RedisStore) Announced(ctx context.Context) ([]int64, error) {
+	members, err := s.client.SMembers(ctx, announcedKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int64, 0, len(members))
+	for _, member := range members {
+		if id, err := strconv.ParseInt(member, 10, 64); err == nil {
+			userIDs = append(userIDs, id)
+		}
+	}
+	return userIDs, nil
+}
+
+// PresenceChanged appends a status change to ChangesStream for the user
+// service
+func (s *RedisStore) PresenceChanged(ctx context.Context, presence domain.Presence) error {
+	data, err := json.Marshal(presence)
+	if err != nil {
+		return err
+	}
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: ChangesStream,
+		MaxLen: changesMaxLen,
+		Approx: true,
+		Values: map[string]any{"presence": data},
+	}).Err()
+}
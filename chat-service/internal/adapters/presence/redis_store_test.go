@@ -0,0 +1,100 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+func newTestStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client), server
+}
+
+func online(state ports.PresenceState) domain.PresenceStatus {
+	if state.Connected {
+		return domain.PresenceOnline
+	}
+	return domain.PresenceOffline
+}
+
+func TestRedisStore_UpdateStatus(t *testing.T) {
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Heartbeat(ctx, 1, "phone", time.Minute, true))
+
+	state, previous, err := store.UpdateStatus(ctx, 1, online)
+	require.NoError(t, err)
+	assert.True(t, state.Connected)
+	assert.False(t, state.LastActiveAt.IsZero())
+	assert.Equal(t, domain.PresenceOffline, previous)
+
+	announced, err := store.Announced(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, announced)
+
+	require.NoError(t, store.Disconnect(ctx, 1, "phone"))
+	_, previous, err = store.UpdateStatus(ctx, 1, online)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PresenceOnline, previous)
+
+	announced, err = store.Announced(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, announced)
+}
+
+func TestRedisStore_ConcurrentUpdatesSeeTheChangeOnce(t *testing.T) {
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+	require.NoError(t, store.Heartbeat(ctx, 1, "phone", time.Minute, true))
+
+	// Every instance refreshing the user at once computes the same status,
+	// only one of them may see it as a change
+	var changes atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, previous, err := store.UpdateStatus(ctx, 1, online)
+			if assert.NoError(t, err) && previous != domain.PresenceOnline {
+				changes.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), changes.Load())
+}
+
+func TestRedisStore_PresenceChangedAppendsToStream(t *testing.T) {
+	store, server := newTestStore(t)
+	lastSeen := time.Now().Truncate(time.Second)
+
+	err := store.PresenceChanged(context.Background(), domain.Presence{UserID: 1, Status: domain.PresenceOffline, LastSeenAt: &lastSeen})
+	require.NoError(t, err)
+
+	entries, err := server.Stream(ChangesStream)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	var presence domain.Presence
+	require.NoError(t, json.Unmarshal([]byte(entries[0].Values[1]), &presence))
+	assert.Equal(t, int64(1), presence.UserID)
+	assert.Equal(t, domain.PresenceOffline, presence.Status)
+	assert.True(t, presence.LastSeenAt.Equal(lastSeen))
+}
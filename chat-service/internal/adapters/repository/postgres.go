@@ -3,60 +3,636 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"html"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
 	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
 )
 
+// dbtx is what the queries need from *sqlx.DB and *sqlx.Tx alike, so the
+// same repository code runs inside and outside transactions
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 type PostgresRepository struct {
-	db *sqlx.DB
+	db   dbtx
+	conn *sqlx.DB // nil for a repository bound to a transaction
 }
 
 func NewPostgresRepository(db *sqlx.DB) ports.ChatRepository {
-	return &PostgresRepository{db: db}
+	return &PostgresRepository{db: db, conn: db}
+}
+
+func (r *PostgresRepository) WithinTransaction(ctx context.Context, fn func(repo ports.ChatRepository) error) error {
+	// Nested units of work join the outer transaction
+	if r.conn == nil {
+		return fn(r)
+	}
+
+	tx, err := r.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// No-op once committed, rolls back on errors and panics
+	defer tx.Rollback()
+
+	if err := fn(&PostgresRepository{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *PostgresRepository) CreateConversation(ctx context.Context, conv *domain.Conversation) error {
-	query := `INSERT INTO conversations (is_group, created_at) VALUES ($1, $2) RETURNING id`
-	return r.db.QueryRowContext(ctx, query, conv.IsGroup, conv.CreatedAt).Scan(&conv.ID)
+	query := `INSERT INTO conversations (is_group, title, created_by, created_at) VALUES ($1, $2, $3, $4) RETURNING id`
+	return r.db.QueryRowContext(ctx, query, conv.IsGroup, conv.Title, conv.CreatedBy, conv.CreatedAt).Scan(&conv.ID)
+}
+
+func (r *PostgresRepository) CreateOneToOneConversation(ctx context.Context, conv *domain.Conversation, user1, user2 int64) (bool, error) {
+	// ON CONFLICT waits for a concurrent creator of the same pair and then
+	// inserts nothing, without aborting the caller's transaction
+	query := `
+		INSERT INTO conversations (is_group, created_at, pair_low, pair_high)
+		VALUES (false, $1, LEAST($2::bigint, $3::bigint), GREATEST($2::bigint, $3::bigint))
+		ON CONFLICT (pair_low, pair_high) WHERE is_group = false DO NOTHING
+		RETURNING id
+	`
+	err := r.db.QueryRowContext(ctx, query, conv.CreatedAt, user1, user2).Scan(&conv.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
 }
 
 func (r *PostgresRepository) AddParticipant(ctx context.Context, part *domain.Participant) error {
-	query := `INSERT INTO participants (conversation_id, user_id, joined_at) VALUES ($1, $2, $3)`
-	_, err := r.db.ExecContext(ctx, query, part.ConversationID, part.UserID, part.JoinedAt)
+	if part.Role == "" {
+		part.Role = domain.RoleMember
+	}
+	// Members start with everything sent before they joined read, which is
+	// what their unread count starts from
+	query := `
+		INSERT INTO participants (conversation_id, user_id, role, joined_at, delivered_seq, read_seq)
+		SELECT $1, $2, $3, $4, last_seq, last_seq FROM conversations WHERE id = $1
+	`
+	_, err := r.db.ExecContext(ctx, query, part.ConversationID, part.UserID, part.Role, part.JoinedAt)
+	return err
+}
+
+func (r *PostgresRepository) GetParticipant(ctx context.Context, conversationID, userID int64) (*domain.Participant, error) {
+	var part domain.Participant
+	query := `SELECT conversation_id, user_id, role, delivered_seq, read_seq, joined_at FROM participants WHERE conversation_id = $1 AND user_id = $2`
+	err := r.db.GetContext(ctx, &part, query, conversationID, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &part, err
+}
+
+func (r *PostgresRepository) GetParticipants(ctx context.Context, conversationID int64) ([]domain.Participant, error) {
+	var parts []domain.Participant
+	query := `SELECT conversation_id, user_id, role, delivered_seq, read_seq, joined_at FROM participants WHERE conversation_id = $1 ORDER BY joined_at, user_id`
+	err := r.db.SelectContext(ctx, &parts, query, conversationID)
+	return parts, err
+}
+
+func (r *PostgresRepository) GetPeers(ctx context.Context, userID int64) ([]int64, error) {
+	var userIDs []int64
+	query := `
+		SELECT DISTINCT peer.user_id
+		FROM participants me
+		JOIN participants peer ON peer.conversation_id = me.conversation_id
+		WHERE me.user_id = $1 AND peer.user_id <> $1
+	`
+	err := r.db.SelectContext(ctx, &userIDs, query, userID)
+	return userIDs, err
+}
+
+func (r *PostgresRepository) GetParticipantsByConversations(ctx context.Context, conversationIDs []int64) ([]domain.Participant, error) {
+	var parts []domain.Participant
+	query := `
+		SELECT conversation_id, user_id, role, delivered_seq, read_seq, joined_at
+		FROM participants
+		WHERE conversation_id = ANY($1)
+		ORDER BY conversation_id, joined_at, user_id
+	`
+	err := r.db.SelectContext(ctx, &parts, query, pq.Array(conversationIDs))
+	return parts, err
+}
+
+func (r *PostgresRepository) UpdateParticipantSettings(ctx context.Context, conversationID, userID int64, pinned bool, mutedUntil *time.Time) error {
+	query := `UPDATE participants SET pinned = $3, muted_until = $4 WHERE conversation_id = $1 AND user_id = $2`
+	_, err := r.db.ExecContext(ctx, query, conversationID, userID, pinned, mutedUntil)
+	return err
+}
+
+func (r *PostgresRepository) RemoveParticipant(ctx context.Context, conversationID, userID int64) error {
+	query := `DELETE FROM participants WHERE conversation_id = $1 AND user_id = $2`
+	_, err := r.db.ExecContext(ctx, query, conversationID, userID)
 	return err
 }
 
+func (r *PostgresRepository) UpdateParticipantRole(ctx context.Context, conversationID, userID int64, role string) error {
+	query := `UPDATE participants SET role = $3 WHERE conversation_id = $1 AND user_id = $2`
+	_, err := r.db.ExecContext(ctx, query, conversationID, userID, role)
+	return err
+}
+
+func (r *PostgresRepository) UpdateWatermarks(ctx context.Context, conversationID, userID, deliveredSeq, readSeq int64) (*domain.Receipt, error) {
+	receipt := domain.Receipt{ConversationID: conversationID, UserID: userID}
+	// The messages read now are taken off the unread count, counted the way
+	// SaveMessage, HideMessage and TombstoneMessage keep it
+	query := `
+		UPDATE participants p
+		SET delivered_seq = GREATEST(p.delivered_seq, LEAST($3, c.last_seq)),
+		    read_seq = GREATEST(p.read_seq, LEAST($4, c.last_seq)),
+		    unread_count = GREATEST(p.unread_count - (
+		        SELECT COUNT(*) FROM messages m
+		        WHERE m.conversation_id = p.conversation_id
+		        AND m.seq > p.read_seq AND m.seq <= LEAST($4, c.last_seq)
+		        AND m.sender_id <> p.user_id
+		        AND m.thread_id IS NULL
+		        AND m.deleted_at IS NULL
+		        AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.user_id = p.user_id)
+		    ), 0)
+		FROM conversations c
+		WHERE c.id = p.conversation_id
+		AND p.conversation_id = $1
+		AND p.user_id = $2
+		AND (p.delivered_seq < LEAST($3, c.last_seq) OR p.read_seq < LEAST($4, c.last_seq))
+		RETURNING p.delivered_seq, p.read_seq
+	`
+	err := r.db.QueryRowContext(ctx, query, conversationID, userID, deliveredSeq, readSeq).Scan(&receipt.DeliveredSeq, &receipt.ReadSeq)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
 func (r *PostgresRepository) SaveMessage(ctx context.Context, msg *domain.Message) error {
-	query := `INSERT INTO messages (conversation_id, sender_id, content, created_at) VALUES ($1, $2, $3, $4) RETURNING id`
-	return r.db.QueryRowContext(ctx, query, msg.ConversationID, msg.SenderID, msg.Content, msg.CreatedAt).Scan(&msg.ID)
+	// Bumping last_seq locks the conversation row, so concurrent senders get
+	// consecutive sequence numbers. The message id is drawn up front so the
+	// conversation can point at its latest message in the same statement.
+	// Thread replies stay out of the main history, so they do not become the
+	// inbox preview, move the conversation up or count as unread; they count
+	// on their root.
+	query := `
+		WITH new_message AS (
+			SELECT nextval('messages_id_seq') AS id
+		), next AS (
+			UPDATE conversations
+			SET last_seq = last_seq + 1,
+			    last_message_id = CASE WHEN $6::bigint IS NULL THEN (SELECT id FROM new_message) ELSE last_message_id END
+			WHERE id = $1
+			RETURNING last_seq
+		), activity AS (
+			UPDATE participants
+			SET last_activity_at = $4,
+			    unread_count = unread_count + CASE WHEN user_id <> $2 THEN 1 ELSE 0 END
+			WHERE conversation_id = $1 AND $6::bigint IS NULL
+		), thread AS (
+			UPDATE messages SET reply_count = reply_count + 1 WHERE id = $6
+		)
+		INSERT INTO messages (id, conversation_id, sender_id, content, created_at, seq, reply_to_id, thread_id, client_message_id)
+		SELECT (SELECT id FROM new_message), $1, $2, $3, $4, last_seq, $5, $6, $7 FROM next
+		RETURNING id, seq
+	`
+	err := r.db.QueryRowContext(ctx, query, msg.ConversationID, msg.SenderID, msg.Content, msg.CreatedAt, msg.ReplyToID, msg.ThreadID, msg.ClientMessageID).Scan(&msg.ID, &msg.Seq)
+
+	// The whole statement is rolled back, last_seq included
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_messages_client_id" {
+		return ports.ErrDuplicateMessage
+	}
+	return err
+}
+
+func (r *PostgresRepository) GetMessageByID(ctx context.Context, id int64) (*domain.Message, error) {
+	var msg domain.Message
+	query := `SELECT * FROM messages WHERE id = $1`
+	err := r.db.GetContext(ctx, &msg, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &msg, err
+}
+
+func (r *PostgresRepository) GetMessageByClientID(ctx context.Context, conversationID, senderID int64, clientMessageID string) (*domain.Message, error) {
+	var msg domain.Message
+	query := `SELECT * FROM messages WHERE conversation_id = $1 AND sender_id = $2 AND client_message_id = $3`
+	err := r.db.GetContext(ctx, &msg, query, conversationID, senderID, clientMessageID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Answer a retry exactly like the original send
+	messages := []domain.Message{msg}
+	if err := r.attachAttachments(ctx, messages); err != nil {
+		return nil, err
+	}
+	return &messages[0], nil
+}
+
+func (r *PostgresRepository) UpdateMessageContent(ctx context.Context, messageID int64, content string, editedAt time.Time) (*domain.Message, error) {
+	var msg domain.Message
+	// Tombstones are never edited, even when deleted after the caller looked
+	// at the message
+	query := `
+		WITH previous AS (
+			INSERT INTO message_revisions (message_id, content, written_at)
+			SELECT id, content, COALESCE(edited_at, created_at)
+			FROM messages
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE
+		)
+		UPDATE messages SET content = $2, edited_at = $3
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING *
+	`
+	err := r.db.GetContext(ctx, &msg, query, messageID, content, editedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &msg, err
+}
+
+func (r *PostgresRepository) HideMessage(ctx context.Context, messageID, userID int64, hiddenAt time.Time) error {
+	// A hidden message that was still unread no longer counts
+	query := `
+		WITH hidden AS (
+			INSERT INTO hidden_messages (message_id, user_id, hidden_at) VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+			RETURNING message_id, user_id
+		)
+		UPDATE participants p
+		SET unread_count = GREATEST(p.unread_count - 1, 0)
+		FROM hidden h
+		JOIN messages m ON m.id = h.message_id
+		WHERE p.conversation_id = m.conversation_id AND p.user_id = h.user_id
+		AND m.seq > p.read_seq
+		AND m.sender_id <> p.user_id
+		AND m.thread_id IS NULL
+		AND m.deleted_at IS NULL
+	`
+	_, err := r.db.ExecContext(ctx, query, messageID, userID, hiddenAt)
+	return err
+}
+
+func (r *PostgresRepository) TombstoneMessage(ctx context.Context, messageID int64, deletedAt time.Time) (*domain.Message, error) {
+	var msg domain.Message
+	// Earlier revisions and reactions go too, nothing of the deleted
+	// content may remain. Whoever had not read the message yet has one
+	// unread message less.
+	query := `
+		WITH revisions AS (
+			DELETE FROM message_revisions WHERE message_id = $1
+		), reactions AS (
+			DELETE FROM reactions WHERE message_id = $1
+		), tombstoned AS (
+			UPDATE messages SET content = '', deleted_at = $2
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING *
+		), unread AS (
+			UPDATE participants p
+			SET unread_count = GREATEST(p.unread_count - 1, 0)
+			FROM tombstoned t
+			WHERE p.conversation_id = t.conversation_id
+			AND t.seq > p.read_seq
+			AND t.sender_id <> p.user_id
+			AND t.thread_id IS NULL
+			AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = t.id AND h.user_id = p.user_id)
+		)
+		SELECT * FROM tombstoned
+	`
+	err := r.db.GetContext(ctx, &msg, query, messageID, deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &msg, err
+}
+
+func (r *PostgresRepository) DeleteMessageAttachments(ctx context.Context, messageID int64) ([]domain.Attachment, error) {
+	var attachments []domain.Attachment
+	query := `DELETE FROM attachments WHERE message_id = $1 RETURNING ` + attachmentColumns
+	if err := r.db.SelectContext(ctx, &attachments, query, messageID); err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		attachments[i].HasThumbnail = attachments[i].ThumbnailKey != nil
+	}
+	return attachments, nil
 }
 
-func (r *PostgresRepository) GetMessages(ctx context.Context, conversationID int64, limit, offset int) ([]domain.Message, error) {
+func (r *PostgresRepository) GetMessageRevisions(ctx context.Context, messageID int64) ([]domain.MessageRevision, error) {
+	var revisions []domain.MessageRevision
+	query := `SELECT id, message_id, content, written_at FROM message_revisions WHERE message_id = $1 ORDER BY written_at, id`
+	err := r.db.SelectContext(ctx, &revisions, query, messageID)
+	return revisions, err
+}
+
+func (r *PostgresRepository) GetMessages(ctx context.Context, conversationID int64, query domain.HistoryQuery) ([]domain.Message, error) {
+	var cursorTime *time.Time
+	var cursorID int64
+	if query.Cursor != nil {
+		cursorTime = &query.Cursor.Time
+		cursorID = query.Cursor.ID
+	}
+
+	// Keyset paging: rows are never skipped or repeated when new messages
+	// arrive between two pages. Tombstones are kept, only messages the
+	// viewer deleted for themselves are skipped.
+	sqlQuery := `
+		SELECT * FROM messages m
+		WHERE conversation_id = $1
+		AND thread_id IS NOT DISTINCT FROM $6
+		AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3))
+		AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.user_id = $5 AND h.message_id = m.id)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`
+	if query.Direction == domain.DirectionForward {
+		sqlQuery = `
+			SELECT * FROM messages m
+			WHERE conversation_id = $1
+			AND thread_id IS NOT DISTINCT FROM $6
+			AND ($2::timestamptz IS NULL OR (created_at, id) > ($2, $3))
+			AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.user_id = $5 AND h.message_id = m.id)
+			ORDER BY created_at, id
+			LIMIT $4
+		`
+	}
+
+	var messages []domain.Message
+	if err := r.db.SelectContext(ctx, &messages, sqlQuery, conversationID, cursorTime, cursorID, query.Limit, query.ViewerID, query.ThreadID); err != nil {
+		return nil, err
+	}
+	if err := r.attachReactions(ctx, messages, query.ViewerID); err != nil {
+		return nil, err
+	}
+	if err := r.attachAttachments(ctx, messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// attachmentColumns lists the attachments columns in domain.Attachment order
+const attachmentColumns = `id, uploader_id, conversation_id, message_id, file_name, mime_type, size, checksum, storage_key, thumbnail_key, created_at`
+
+// attachAttachments fills in the attachments of a page of messages with a
+// single query. Tombstones never carry attachments.
+func (r *PostgresRepository) attachAttachments(ctx context.Context, messages []domain.Message) error {
+	ids := make([]int64, 0, len(messages))
+	for _, msg := range messages {
+		if msg.DeletedAt == nil {
+			ids = append(ids, msg.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var attachments []domain.Attachment
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE message_id = ANY($1) ORDER BY id`
+	if err := r.db.SelectContext(ctx, &attachments, query, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	byMessage := make(map[int64][]domain.Attachment)
+	for _, attachment := range attachments {
+		attachment.HasThumbnail = attachment.ThumbnailKey != nil
+		byMessage[*attachment.MessageID] = append(byMessage[*attachment.MessageID], attachment)
+	}
+	for i := range messages {
+		messages[i].Attachments = byMessage[messages[i].ID]
+	}
+	return nil
+}
+
+func (r *PostgresRepository) SaveAttachment(ctx context.Context, attachment *domain.Attachment) error {
+	query := `
+		INSERT INTO attachments (uploader_id, file_name, mime_type, size, checksum, storage_key, thumbnail_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	return r.db.QueryRowContext(ctx, query,
+		attachment.UploaderID, attachment.FileName, attachment.MimeType, attachment.Size,
+		attachment.Checksum, attachment.StorageKey, attachment.ThumbnailKey, attachment.CreatedAt,
+	).Scan(&attachment.ID)
+}
+
+func (r *PostgresRepository) GetAttachment(ctx context.Context, id int64) (*domain.Attachment, error) {
+	var attachment domain.Attachment
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+	err := r.db.GetContext(ctx, &attachment, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	attachment.HasThumbnail = attachment.ThumbnailKey != nil
+	return &attachment, nil
+}
+
+func (r *PostgresRepository) GetAttachments(ctx context.Context, ids []int64) ([]domain.Attachment, error) {
+	var attachments []domain.Attachment
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = ANY($1) ORDER BY id`
+	if err := r.db.SelectContext(ctx, &attachments, query, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		attachments[i].HasThumbnail = attachments[i].ThumbnailKey != nil
+	}
+	return attachments, nil
+}
+
+func (r *PostgresRepository) LinkAttachments(ctx context.Context, messageID, conversationID, uploaderID int64, ids []int64) (int64, error) {
+	query := `
+		UPDATE attachments SET message_id = $1, conversation_id = $2
+		WHERE id = ANY($4) AND uploader_id = $3 AND message_id IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, messageID, conversationID, uploaderID, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// attachReactions fills in the reaction counts of a page of messages with
+// a single query
+func (r *PostgresRepository) attachReactions(ctx context.Context, messages []domain.Message, viewerID int64) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+
+	var counts []domain.ReactionCount
+	query := `
+		SELECT message_id, emoji, COUNT(*) AS count, BOOL_OR(user_id = $2) AS reacted
+		FROM reactions
+		WHERE message_id = ANY($1)
+		GROUP BY message_id, emoji
+		ORDER BY message_id, MIN(created_at), emoji
+	`
+	if err := r.db.SelectContext(ctx, &counts, query, pq.Array(ids), viewerID); err != nil {
+		return err
+	}
+
+	byMessage := make(map[int64][]domain.ReactionCount)
+	for _, count := range counts {
+		byMessage[count.MessageID] = append(byMessage[count.MessageID], count)
+	}
+	for i := range messages {
+		messages[i].Reactions = byMessage[messages[i].ID]
+	}
+	return nil
+}
+
+func (r *PostgresRepository) AddReaction(ctx context.Context, reaction *domain.Reaction) (bool, error) {
+	query := `INSERT INTO reactions (message_id, user_id, emoji, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`
+	res, err := r.db.ExecContext(ctx, query, reaction.MessageID, reaction.UserID, reaction.Emoji, reaction.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *PostgresRepository) RemoveReaction(ctx context.Context, messageID, userID int64, emoji string) (bool, error) {
+	query := `DELETE FROM reactions WHERE message_id = $1 AND user_id = $2 AND emoji = $3`
+	res, err := r.db.ExecContext(ctx, query, messageID, userID, emoji)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *PostgresRepository) GetThreadParticipants(ctx context.Context, rootID int64) ([]int64, error) {
+	var userIDs []int64
+	query := `SELECT DISTINCT sender_id FROM messages WHERE id = $1 OR thread_id = $1`
+	err := r.db.SelectContext(ctx, &userIDs, query, rootID)
+	return userIDs, err
+}
+
+// Search snippets are highlighted with control characters that cannot be
+// confused with content, then HTML-escaped and turned into <mark> tags
+const (
+	highlightStart  = "\x02"
+	highlightStop   = "\x03"
+	headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=2, MaxWords=20, MinWords=8, FragmentDelimiter=\" … \""
+)
+
+var snippetReplacer = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+func (r *PostgresRepository) SearchMessages(ctx context.Context, query domain.SearchQuery) ([]domain.SearchHit, error) {
+	var cursorTime *time.Time
+	var cursorID int64
+	if query.Cursor != nil {
+		cursorTime = &query.Cursor.Time
+		cursorID = query.Cursor.ID
+	}
+
+	// to_tsvector('simple', content) must match idx_messages_search.
+	// Hits are ordered by time rather than rank so keyset paging stays
+	// stable while new messages arrive.
+	sqlQuery := `
+		SELECT m.*, ts_headline('simple', m.content, q.query, $9) AS snippet
+		FROM messages m
+		JOIN participants p ON p.conversation_id = m.conversation_id AND p.user_id = $1
+		CROSS JOIN websearch_to_tsquery('simple', $2) AS q(query)
+		WHERE to_tsvector('simple', m.content) @@ q.query
+		AND m.deleted_at IS NULL
+		AND ($3::bigint IS NULL OR m.conversation_id = $3)
+		AND ($4::bigint IS NULL OR m.sender_id = $4)
+		AND ($5::timestamptz IS NULL OR m.created_at >= $5)
+		AND ($6::timestamptz IS NULL OR m.created_at < $6)
+		AND ($7::timestamptz IS NULL OR (m.created_at, m.id) < ($7, $8))
+		AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.user_id = $1 AND h.message_id = m.id)
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $10
+	`
+
+	var hits []domain.SearchHit
+	err := r.db.SelectContext(ctx, &hits, sqlQuery,
+		query.ViewerID, query.Text, query.ConversationID, query.SenderID,
+		query.From, query.To, cursorTime, cursorID, headlineOptions, query.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].Snippet = snippetReplacer.Replace(html.EscapeString(hits[i].Snippet))
+	}
+	return hits, nil
+}
+
+func (r *PostgresRepository) GetMessagesSince(ctx context.Context, userID int64, cursor domain.SyncCursor, limit int) ([]domain.Message, error) {
+	convIDs := make([]int64, 0, len(cursor))
+	seqs := make([]int64, 0, len(cursor))
+	for convID, seq := range cursor {
+		convIDs = append(convIDs, convID)
+		seqs = append(seqs, seq)
+	}
+
 	var messages []domain.Message
-	query := `SELECT * FROM messages WHERE conversation_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
-	err := r.db.SelectContext(ctx, &messages, query, conversationID, limit, offset)
+	query := `
+		SELECT m.*
+		FROM participants p
+		JOIN conversations c ON c.id = p.conversation_id
+		LEFT JOIN unnest($2::bigint[], $3::bigint[]) AS cur(conversation_id, seq)
+			ON cur.conversation_id = p.conversation_id
+		CROSS JOIN LATERAL (
+			SELECT * FROM messages mm
+			WHERE conversation_id = p.conversation_id
+			AND seq > COALESCE(cur.seq, GREATEST(c.last_seq - $4, 0))
+			AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.user_id = $1 AND h.message_id = mm.id)
+			ORDER BY seq
+			LIMIT $4
+		) m
+		WHERE p.user_id = $1
+		AND c.last_seq > COALESCE(cur.seq, 0)
+		ORDER BY m.conversation_id, m.seq
+	`
+	err := r.db.SelectContext(ctx, &messages, query, userID, pq.Array(convIDs), pq.Array(seqs), limit)
 	return messages, err
 }
 
 func (r *PostgresRepository) GetConversationByID(ctx context.Context, id int64) (*domain.Conversation, error) {
 	var conv domain.Conversation
-	query := `SELECT * FROM conversations WHERE id = $1`
+	query := `SELECT id, is_group, title, created_by, last_seq, created_at FROM conversations WHERE id = $1`
 	err := r.db.GetContext(ctx, &conv, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	return &conv, err
 }
 
 func (r *PostgresRepository) FindOneToOneConversation(ctx context.Context, user1, user2 int64) (*domain.Conversation, error) {
 	var conv domain.Conversation
 	query := `
-		SELECT c.* 
-		FROM conversations c
-		JOIN participants p1 ON c.id = p1.conversation_id
-		JOIN participants p2 ON c.id = p2.conversation_id
-		WHERE c.is_group = false 
-		AND p1.user_id = $1 
-		AND p2.user_id = $2
+		SELECT id, is_group, title, created_by, last_seq, created_at
+		FROM conversations
+		WHERE is_group = false
+		AND pair_low = LEAST($1::bigint, $2::bigint)
+		AND pair_high = GREATEST($1::bigint, $2::bigint)
 	`
 	err := r.db.GetContext(ctx, &conv, query, user1, user2)
 	if err == sql.ErrNoRows {
@@ -64,3 +640,79 @@ func (r *PostgresRepository) FindOneToOneConversation(ctx context.Context, user1
 	}
 	return &conv, err
 }
+
+// inboxRow is the flat shape of an inbox query row; the last message
+// columns are NULL for conversations without messages
+type inboxRow struct {
+	domain.Conversation
+	LastActivityAt   time.Time      `db:"last_activity_at"`
+	MutedUntil       *time.Time     `db:"muted_until"`
+	Pinned           bool           `db:"pinned"`
+	UnreadCount      int64          `db:"unread_count"`
+	MessageID        sql.NullInt64  `db:"message_id"`
+	MessageSenderID  sql.NullInt64  `db:"message_sender_id"`
+	MessageContent   sql.NullString `db:"message_content"`
+	MessageSeq       sql.NullInt64  `db:"message_seq"`
+	MessageCreatedAt sql.NullTime   `db:"message_created_at"`
+	MessageEditedAt  *time.Time     `db:"message_edited_at"`
+	MessageDeletedAt *time.Time     `db:"message_deleted_at"`
+}
+
+func (r *PostgresRepository) GetInbox(ctx context.Context, userID int64, after *domain.PageCursor, limit int) ([]domain.InboxEntry, error) {
+	var afterTime *time.Time
+	var afterID int64
+	var afterPinned bool
+	if after != nil {
+		afterTime = &after.Time
+		afterID = after.ID
+		afterPinned = after.Pinned
+	}
+
+	// Pinned conversations first, then by activity; both keyset columns
+	// follow the inbox index
+	var rows []inboxRow
+	query := `
+		SELECT c.id, c.is_group, c.title, c.created_by, c.last_seq, c.created_at,
+		       p.last_activity_at, p.muted_until, p.pinned, p.unread_count,
+		       m.id AS message_id, m.sender_id AS message_sender_id, m.content AS message_content,
+		       m.seq AS message_seq, m.created_at AS message_created_at, m.edited_at AS message_edited_at,
+		       m.deleted_at AS message_deleted_at
+		FROM participants p
+		JOIN conversations c ON c.id = p.conversation_id
+		LEFT JOIN messages m ON m.id = c.last_message_id
+		WHERE p.user_id = $1
+		AND ($2::timestamptz IS NULL
+		     OR (p.pinned = $4 AND (p.last_activity_at, p.conversation_id) < ($2, $3))
+		     OR (NOT p.pinned AND $4))
+		ORDER BY p.pinned DESC, p.last_activity_at DESC, p.conversation_id DESC
+		LIMIT $5
+	`
+	if err := r.db.SelectContext(ctx, &rows, query, userID, afterTime, afterID, afterPinned, limit); err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.InboxEntry, 0, len(rows))
+	for _, row := range rows {
+		entry := domain.InboxEntry{
+			Conversation:   row.Conversation,
+			UnreadCount:    row.UnreadCount,
+			MutedUntil:     row.MutedUntil,
+			Pinned:         row.Pinned,
+			LastActivityAt: row.LastActivityAt,
+		}
+		if row.MessageID.Valid {
+			entry.LastMessage = &domain.Message{
+				ID:             row.MessageID.Int64,
+				ConversationID: row.ID,
+				SenderID:       row.MessageSenderID.Int64,
+				Content:        row.MessageContent.String,
+				Seq:            row.MessageSeq.Int64,
+				CreatedAt:      row.MessageCreatedAt.Time,
+				EditedAt:       row.MessageEditedAt,
+				DeletedAt:      row.MessageDeletedAt,
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,132 @@
+package userclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	userpb "github.com/zhanserikAmangeldi/proto/user"
+)
+
+// maxBatchSize is the most users the user service returns per call
+const maxBatchSize = 500
+
+// Config tells the client where the user service is and how patient to be
+type Config struct {
+	Addr        string
+	Timeout     time.Duration // deadline of a whole call, retries included
+	MaxAttempts int           // attempts per call while the service is unavailable
+	Token       string        // service token the user service requires on every call
+}
+
+// retryPolicy retries every UserService call (all of them are reads) while
+// the user service is unavailable, with exponential backoff
+const retryPolicy = `{
+	"methodConfig": [{
+		"name": [{"service": "user.v1.UserService"}],
+		"retryPolicy": {
+			"maxAttempts": %d,
+			"initialBackoff": "0.1s",
+			"maxBackoff": "1s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`
+
+// Client talks to the user service over gRPC. It implements
+// ports.UserDirectory.
+type Client struct {
+	conn    *grpc.ClientConn
+	users   userpb.UserServiceClient
+	timeout time.Duration
+}
+
+// New connects lazily, so the user service does not have to be up yet.
+// Extra dial options are mostly useful in tests.
+func New(cfg Config, opts ...grpc.DialOption) (*Client, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.MaxAttempts < 2 {
+		cfg.MaxAttempts = 2
+	}
+
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(retryPolicy, cfg.MaxAttempts)),
+		grpc.WithPerRPCCredentials(serviceToken(cfg.Token)),
+	}, opts...)
+
+	conn, err := grpc.NewClient(cfg.Addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		conn:    conn,
+		users:   userpb.NewUserServiceClient(conn),
+		timeout: cfg.Timeout,
+	}, nil
+}
+
+// serviceToken sends the service token as a bearer token with every call.
+// The services talk over the internal network, hence no transport security.
+type serviceToken string
+
+func (t serviceToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (serviceToken) RequireTransportSecurity() bool {
+	return false
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) UserExists(ctx context.Context, userID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.users.CheckUserExists(ctx, &userpb.CheckUserExistsRequest{UserId: userID})
+	if err != nil {
+		return false, fmt.Errorf("check user %d: %w", userID, err)
+	}
+	return resp.GetExists(), nil
+}
+
+func (c *Client) GetUsers(ctx context.Context, userIDs []int64) (map[int64]domain.UserProfile, error) {
+	profiles := make(map[int64]domain.UserProfile, len(userIDs))
+	for start := 0; start < len(userIDs); start += maxBatchSize {
+		end := min(start+maxBatchSize, len(userIDs))
+		if err := c.getBatch(ctx, userIDs[start:end], profiles); err != nil {
+			return nil, err
+		}
+	}
+	return profiles, nil
+}
+
+func (c *Client) getBatch(ctx context.Context, userIDs []int64, profiles map[int64]domain.UserProfile) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.users.BatchGetUsers(ctx, &userpb.BatchGetUsersRequest{UserIds: userIDs})
+	if err != nil {
+		return fmt.Errorf("get users: %w", err)
+	}
+	for _, user := range resp.GetUsers() {
+		profiles[user.GetId()] = domain.UserProfile{
+			ID:          user.GetId(),
+			Username:    user.GetUsername(),
+			DisplayName: user.GetDisplayName(),
+			AvatarURL:   user.GetAvatarUrl(),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package userclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userpb "github.com/zhanserikAmangeldi/proto/user"
+)
+
+func newTestClient(t *testing.T, fake *FakeServer, cfg Config) *Client {
+	dial, stop := fake.Start()
+	t.Cleanup(stop)
+
+	cfg.Addr = "passthrough:///user-service"
+	client, err := New(cfg, dial)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClient_LooksUsersUp(t *testing.T) {
+	fake := NewFakeServer(
+		&userpb.User{Id: 1, Username: "alice", DisplayName: "Alice", AvatarUrl: "https://cdn/alice.png"},
+		&userpb.User{Id: 2, Username: "bob"},
+	)
+	client := newTestClient(t, fake, Config{})
+	ctx := context.Background()
+
+	exists, err := client.UserExists(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = client.UserExists(ctx, 9)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	profiles, err := client.GetUsers(ctx, []int64{1, 2, 9})
+	assert.NoError(t, err)
+	assert.Len(t, profiles, 2)
+	assert.Equal(t, "Alice", profiles[1].DisplayName)
+	assert.Equal(t, "https://cdn/alice.png", profiles[1].AvatarURL)
+	assert.Equal(t, "bob", profiles[2].Username)
+}
+
+func TestClient_RetriesWhileUnavailable(t *testing.T) {
+	fake := NewFakeServer(&userpb.User{Id: 1, Username: "alice"})
+	client := newTestClient(t, fake, Config{MaxAttempts: 3})
+
+	fake.FailNext(2)
+	exists, err := client.UserExists(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 3, fake.Calls())
+}
+
+func TestClient_GivesUpAfterMaxAttempts(t *testing.T) {
+	fake := NewFakeServer(&userpb.User{Id: 1, Username: "alice"})
+	client := newTestClient(t, fake, Config{MaxAttempts: 2})
+
+	fake.FailNext(5)
+	_, err := client.UserExists(context.Background(), 1)
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 2, fake.Calls())
+}
+
+func TestClient_EnforcesDeadline(t *testing.T) {
+	fake := NewFakeServer(&userpb.User{Id: 1, Username: "alice"})
+	client := newTestClient(t, fake, Config{Timeout: 50 * time.Millisecond})
+
+	fake.SetDelay(time.Second)
+	started := time.Now()
+	_, err := client.GetUsers(context.Background(), []int64{1})
+
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(started), 500*time.Millisecond)
+}
+
+func TestClient_SendsServiceToken(t *testing.T) {
+	fake := NewFakeServer(&userpb.User{Id: 1, Username: "alice"})
+	fake.RequireToken("s3cret")
+	ctx := context.Background()
+
+	exists, err := newTestClient(t, fake, Config{Token: "s3cret"}).UserExists(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	_, err = newTestClient(t, fake, Config{Token: "wrong"}).UserExists(ctx, 1)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
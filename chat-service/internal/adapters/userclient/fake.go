@@ -0,0 +1,181 @@
+package userclient
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	userpb "github.com/zhanserikAmangeldi/proto/user"
+)
+
+// FakeServer is an in-memory user service for tests. It can be told to
+// fail or stall to exercise retries and deadlines.
+type FakeServer struct {
+	userpb.UnimplementedUserServiceServer
+
+	mu       sync.Mutex
+	token    string
+	users    map[int64]*userpb.User
+	tokens   map[string]int64
+	failures int
+	delay    time.Duration
+	calls    int
+}
+
+func NewFakeServer(users ...*userpb.User) *FakeServer {
+	f := &FakeServer{
+		users:  make(map[int64]*userpb.User),
+		tokens: make(map[string]int64),
+	}
+	for _, user := range users {
+		f.users[user.GetId()] = user
+	}
+	return f
+}
+
+// AddToken makes ValidateToken accept token as belonging to userID
+func (f *FakeServer) AddToken(token string, userID int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens[token] = userID
+}
+
+// RequireToken makes every call without token as its bearer token fail
+// with UNAUTHENTICATED
+func (f *FakeServer) RequireToken(token string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.token = token
+}
+
+// FailNext makes the next n calls fail with UNAVAILABLE
+func (f *FakeServer) FailNext(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = n
+}
+
+// SetDelay makes every call take at least d
+func (f *FakeServer) SetDelay(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delay = d
+}
+
+// Calls is the number of calls received so far, failed ones included
+func (f *FakeServer) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// Start serves f on an in-memory listener. The returned dial option makes
+// a client connect to it whatever address it is given; stop shuts it down.
+func (f *FakeServer) Start() (dial grpc.DialOption, stop func()) {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	userpb.RegisterUserServiceServer(server, f)
+	go server.Serve(listener)
+
+	dial = grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	})
+	return dial, server.Stop
+}
+
+func (f *FakeServer) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	if err := f.call(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[req.GetUserId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "user %d not found", req.GetUserId())
+	}
+	return &userpb.GetUserResponse{User: user}, nil
+}
+
+func (f *FakeServer) BatchGetUsers(ctx context.Context, req *userpb.BatchGetUsersRequest) (*userpb.BatchGetUsersResponse, error) {
+	if err := f.call(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resp := &userpb.BatchGetUsersResponse{}
+	for _, userID := range req.GetUserIds() {
+		if user, ok := f.users[userID]; ok {
+			resp.Users = append(resp.Users, user)
+		}
+	}
+	return resp, nil
+}
+
+func (f *FakeServer) ValidateToken(ctx context.Context, req *userpb.ValidateTokenRequest) (*userpb.ValidateTokenResponse, error) {
+	if err := f.call(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	userID, ok := f.tokens[req.GetToken()]
+	if !ok {
+		return &userpb.ValidateTokenResponse{}, nil
+	}
+	resp := &userpb.ValidateTokenResponse{Valid: true, UserId: userID}
+	if user, ok := f.users[userID]; ok {
+		resp.Username = user.GetUsername()
+	}
+	return resp, nil
+}
+
+func (f *FakeServer) CheckUserExists(ctx context.Context, req *userpb.CheckUserExistsRequest) (*userpb.CheckUserExistsResponse, error) {
+	if err := f.call(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.users[req.GetUserId()]
+	return &userpb.CheckUserExistsResponse{Exists: ok}, nil
+}
+
+// call counts a call and applies the configured failure and delay
+func (f *FakeServer) call(ctx context.Context) error {
+	f.mu.Lock()
+	f.calls++
+	token := f.token
+	fail := f.failures > 0
+	if fail {
+		f.failures--
+	}
+	delay := f.delay
+	f.mu.Unlock()
+
+	if token != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get("authorization"); len(values) != 1 || values[0] != "Bearer "+token {
+			return status.Error(codes.Unauthenticated, "missing or invalid service token")
+		}
+	}
+	if fail {
+		return status.Error(codes.Unavailable, "user service is down")
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	return nil
+}
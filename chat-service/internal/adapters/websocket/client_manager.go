@@ -1,46 +1,320 @@
 package websocket
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// OverflowPolicy decides what happens when a client's send buffer is full
+type OverflowPolicy string
+
+const (
+	// PolicyDrop drops the frame that did not fit and keeps the connection
+	PolicyDrop OverflowPolicy = "drop"
+	// PolicyDisconnect closes the slow connection, the client is expected
+	// to reconnect and catch up from history
+	PolicyDisconnect OverflowPolicy = "disconnect"
+)
+
+// ClientConfig tunes the per-connection send buffers and keepalives
+type ClientConfig struct {
+	SendBufferSize int
+	OverflowPolicy OverflowPolicy
+	// PingInterval is how often the server pings; it must be shorter than PongWait
+	PingInterval time.Duration
+	// PongWait is how long a connection may stay silent before it is reaped
+	PongWait time.Duration
+	// WriteWait bounds every single write, including pings
+	WriteWait time.Duration
+	// MaxMessageSize is the largest inbound frame accepted, in bytes
+	MaxMessageSize int64
+}
+
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		SendBufferSize: 256,
+		OverflowPolicy: PolicyDisconnect,
+		PingInterval:   30 * time.Second,
+		PongWait:       60 * time.Second,
+		WriteWait:      10 * time.Second,
+		MaxMessageSize: 64 * 1024,
+	}
+}
+
+// Client is a single WebSocket connection of a user. A user can hold
+// several clients at once, one per device or browser tab.
+//
+// Gorilla connections support one concurrent writer only, so every write
+// goes through Send and is performed by the client's own write pump.
+type Client struct {
+	ID     string
+	UserID int64
+	// Conn is only read from directly; never write to it, use Send
+	Conn *websocket.Conn
+
+	config    ClientConfig
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Send queues data for the write pump without blocking. It returns false
+// if the client is closed or its buffer is full.
+func (c *Client) Send(data []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReadPump reads frames and passes them to handle until the connection
+// fails. Any frame or pong pushes the read deadline forward, so a peer that
+// stops answering pings is reaped once PongWait elapses.
+func (c *Client) ReadPump(handle func(data []byte)) error {
+	c.Conn.SetReadLimit(c.config.MaxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(c.config.PongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		metrics.Add("pongs_received", 1)
+		return c.Conn.SetReadDeadline(time.Now().Add(c.config.PongWait))
+	})
+
+	for {
+		_, data, err := c.Conn.ReadMessage()
+		if err != nil {
+			switch {
+			case isTimeout(err):
+				metrics.Add("read_timeouts", 1)
+				log.Printf("Reaping user %d (connection %s): no pong within %s", c.UserID, c.ID, c.config.PongWait)
+			case errors.Is(err, websocket.ErrReadLimit):
+				metrics.Add("oversized_frames", 1)
+				log.Printf("Closing user %d (connection %s): frame exceeds %d bytes", c.UserID, c.ID, c.config.MaxMessageSize)
+			}
+			return err
+		}
+
+		c.Conn.SetReadDeadline(time.Now().Add(c.config.PongWait))
+		handle(data)
+	}
+}
+
+// writePump is the only goroutine that writes to the connection. It also
+// pings the peer every PingInterval.
+func (c *Client) writePump(onError func()) {
+	ticker := time.NewTicker(c.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.send:
+			c.Conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			if err := c.Conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.logWriteError("write", err)
+				onError()
+				return
+			}
+		case <-ticker.C:
+			if err := c.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.config.WriteWait)); err != nil {
+				c.logWriteError("ping", err)
+				onError()
+				return
+			}
+			metrics.Add("pings_sent", 1)
+		}
+	}
+}
+
+func (c *Client) logWriteError(op string, err error) {
+	if isTimeout(err) {
+		metrics.Add("write_timeouts", 1)
+		log.Printf("Reaping user %d (connection %s): %s timed out after %s", c.UserID, c.ID, op, c.config.WriteWait)
+		return
+	}
+	metrics.Add("write_errors", 1)
+	log.Printf("User %d (connection %s) %s failed: %v", c.UserID, c.ID, op, err)
+}
+
+// CloseWithReason tells the peer why the connection ends, with a close
+// frame carrying code and reason, then closes it. ReadPump returns soon after.
+func (c *Client) CloseWithReason(code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	if err := c.Conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.config.WriteWait)); err != nil {
+		c.logWriteError("close", err)
+	}
+	c.close()
+}
+
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.Conn.Close()
+	})
+}
+
 // ClientManager keeps track of all the active websocket connections
 type ClientManager struct {
-	// A map of UserID -> WebSocket Connection
-	clients map[int64]*websocket.Conn
+	// A map of UserID -> ConnectionID -> Client
+	clients map[int64]map[string]*Client
 	// Mutex to protect the map from concurrent writes
-	lock sync.RWMutex
+	lock   sync.RWMutex
+	config ClientConfig
 }
 
 func NewClientManager() *ClientManager {
+	return NewClientManagerWithConfig(DefaultClientConfig())
+}
+
+func NewClientManagerWithConfig(config ClientConfig) *ClientManager {
+	defaults := DefaultClientConfig()
+	if config.SendBufferSize <= 0 {
+		config.SendBufferSize = defaults.SendBufferSize
+	}
+	if config.OverflowPolicy != PolicyDrop {
+		config.OverflowPolicy = PolicyDisconnect
+	}
+	if config.PongWait <= 0 {
+		config.PongWait = defaults.PongWait
+	}
+	if config.PingInterval <= 0 || config.PingInterval >= config.PongWait {
+		config.PingInterval = config.PongWait * 9 / 10
+	}
+	if config.WriteWait <= 0 {
+		config.WriteWait = defaults.WriteWait
+	}
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = defaults.MaxMessageSize
+	}
+
 	return &ClientManager{
-		clients: make(map[int64]*websocket.Conn),
+		clients: make(map[int64]map[string]*Client),
+		config:  config,
 	}
 }
 
-// AddClient registers a user connection
-func (manager *ClientManager) AddClient(userID int64, conn *websocket.Conn) {
+// AddClient registers a new connection for the user, starts its write pump
+// and returns it
+func (manager *ClientManager) AddClient(userID int64, conn *websocket.Conn) *Client {
+	client := &Client{
+		ID:     uuid.NewString(),
+		UserID: userID,
+		Conn:   conn,
+		config: manager.config,
+		send:   make(chan []byte, manager.config.SendBufferSize),
+		done:   make(chan struct{}),
+	}
+
+	metrics.Add("connections_opened", 1)
 	manager.lock.Lock()
-	defer manager.lock.Unlock()
-	manager.clients[userID] = conn
+	if manager.clients[userID] == nil {
+		manager.clients[userID] = make(map[string]*Client)
+	}
+	manager.clients[userID][client.ID] = client
+	manager.lock.Unlock()
+
+	go client.writePump(func() {
+		// If sending fails, maybe they just disconnected
+		manager.RemoveClient(client)
+	})
+
+	return client
 }
 
-// RemoveClient unregisters a user connection
-func (manager *ClientManager) RemoveClient(userID int64) {
+// RemoveClient unregisters a single connection, the user's other
+// connections stay open
+func (manager *ClientManager) RemoveClient(client *Client) {
 	manager.lock.Lock()
 	defer manager.lock.Unlock()
-	if conn, ok := manager.clients[userID]; ok {
-		conn.Close()
-		delete(manager.clients, userID)
+	client.close()
+	conns, ok := manager.clients[client.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := conns[client.ID]; ok {
+		metrics.Add("connections_closed", 1)
+	}
+	delete(conns, client.ID)
+	if len(conns) == 0 {
+		delete(manager.clients, client.UserID)
 	}
 }
 
-// GetClient returns the connection for a specific user
-func (manager *ClientManager) GetClient(userID int64) (*websocket.Conn, bool) {
+// GetClients returns all connections of a specific user
+func (manager *ClientManager) GetClients(userID int64) []*Client {
 	manager.lock.RLock()
 	defer manager.lock.RUnlock()
-	conn, ok := manager.clients[userID]
-	return conn, ok
+	conns := manager.clients[userID]
+	clients := make([]*Client, 0, len(conns))
+	for _, client := range conns {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// IsOnline reports whether the user has at least one open connection
+func (manager *ClientManager) IsOnline(userID int64) bool {
+	manager.lock.RLock()
+	defer manager.lock.RUnlock()
+	return len(manager.clients[userID]) > 0
+}
+
+// SendToUser queues data on every connection of the user. It never blocks:
+// a connection with a full buffer is handled by the overflow policy.
+func (manager *ClientManager) SendToUser(userID int64, data []byte) {
+	for _, client := range manager.GetClients(userID) {
+		manager.SendToClient(client, data)
+	}
+}
+
+// SendToClient queues data on a single connection, applying the overflow
+// policy if its buffer is full
+func (manager *ClientManager) SendToClient(client *Client, data []byte) {
+	if client.Send(data) {
+		return
+	}
+
+	select {
+	case <-client.done:
+		return
+	default:
+	}
+
+	if manager.config.OverflowPolicy == PolicyDrop {
+		metrics.Add("frames_dropped", 1)
+		log.Printf("Send buffer full for user %d (connection %s), dropping frame", client.UserID, client.ID)
+		return
+	}
+
+	metrics.Add("slow_client_disconnects", 1)
+	log.Printf("Send buffer full for user %d (connection %s), disconnecting slow client", client.UserID, client.ID)
+	manager.RemoveClient(client)
+}
+
+// Publish delivers payload to the connections held by this process. It
+// makes ClientManager the single-node, in-memory delivery bus.
+func (manager *ClientManager) Publish(ctx context.Context, userIDs []int64, payload []byte) error {
+	for _, userID := range userIDs {
+		manager.SendToUser(userID, payload)
+	}
+	return nil
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
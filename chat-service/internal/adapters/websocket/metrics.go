@@ -0,0 +1,9 @@
+package websocket
+
+import "expvar"
+
+// metrics publishes connection lifecycle counters through expvar under
+// "websocket": opened/closed connections, pings and pongs, read and write
+// timeouts, oversized frames and overflow policy decisions. They are
+// served on the admin listener only, never on the public port.
+var metrics = expvar.NewMap("websocket")
@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"encoding/json"
+)
+
+// ProtocolVersion is the version of the JSON envelope protocol spoken on /ws
+const ProtocolVersion = 1
+
+// Inbound event types (client -> server)
+const (
+	EventSendMessage = "send_message"
+	EventTyping      = "typing"
+	EventMarkRead    = "mark_read"
+	EventPing        = "ping"
+	// EventMarkDelivered acknowledges delivery without marking as read
+	EventMarkDelivered = "mark_delivered"
+	// EventSync asks for everything missed since a cursor, clients send it
+	// right after connecting
+	EventSync = "sync"
+)
+
+// Outbound event types (server -> client)
+const (
+	EventMessage  = "message"
+	EventAck      = "ack"
+	EventError    = "error"
+	EventPresence = "presence"
+	EventPong     = "pong"
+	// EventReceipt carries a domain.Receipt when a peer's watermark moves
+	EventReceipt = "receipt"
+	// Ephemeral typing indicators, never persisted. typing_stopped is also
+	// sent by the server when the typist stops refreshing the indicator.
+	EventTypingStarted = "typing_started"
+	EventTypingStopped = "typing_stopped"
+	// EventSyncResult answers a sync request with a domain.SyncResult
+	EventSyncResult = "sync_result"
+	// EventMessageEdited carries the edited domain.Message to every
+	// participant, the author's other devices included
+	EventMessageEdited = "message_edited"
+	// EventMessageDeleted carries a domain.MessageDeletion
+	EventMessageDeleted = "message_deleted"
+	// Reaction events carry a domain.Reaction
+	EventReactionAdded   = "reaction_added"
+	EventReactionRemoved = "reaction_removed"
+	// EventThreadReply carries a domain.Message posted in a thread, sent to
+	// the thread's participants only
+	EventThreadReply = "thread_reply"
+)
+
+// Error codes carried in error frames
+const (
+	ErrCodeBadRequest         = "bad_request"
+	ErrCodeUnsupportedVersion = "unsupported_version"
+	ErrCodeUnknownEvent       = "unknown_event"
+	ErrCodeForbidden          = "forbidden"
+	ErrCodeNotFound           = "not_found"
+	ErrCodeInternal           = "internal"
+	ErrCodeUnavailable        = "unavailable"
+)
+
+// CloseTokenRevoked is the close code sent when the token a connection was
+// opened with is revoked; clients must log in again before reconnecting
+const CloseTokenRevoked = 4001
+
+// Envelope is the frame every WebSocket message is wrapped in. ID is the
+// client correlation id; the server echoes it back on ack and error frames.
+type Envelope struct {
+	Version int             `json:"v"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// SendMessagePayload is the payload of send_message. Either RecipientID
+// (1:1 chat) or ConversationID (group chat) must be set, or ReplyToID to
+// answer a message: in its thread with Thread, quoting it otherwise.
+// AttachmentIDs refer to files uploaded beforehand over REST. Resending
+// with the same ClientMessageID (after a lost ack, say) acks the message
+// stored the first time instead of storing it again; the ack carries it back.
+type SendMessagePayload struct {
+	RecipientID     int64   `json:"recipient_id,omitempty"`
+	ConversationID  int64   `json:"conversation_id,omitempty"`
+	ReplyToID       int64   `json:"reply_to_id,omitempty"`
+	Thread          bool    `json:"thread,omitempty"`
+	Content         string  `json:"content"`
+	ClientMessageID string  `json:"client_message_id,omitempty"`
+	AttachmentIDs   []int64 `json:"attachment_ids,omitempty"`
+}
+
+// TypingPayload is the payload of typing (inbound) and of typing_started
+// and typing_stopped (outbound). Clients resend typing=true every few
+// seconds while the user keeps typing.
+type TypingPayload struct {
+	ConversationID int64 `json:"conversation_id"`
+	UserID         int64 `json:"user_id,omitempty"`
+	Typing         bool  `json:"typing"`
+}
+
+// MarkReadPayload is the payload of mark_read and mark_delivered: every
+// message of the conversation up to Seq is acknowledged
+type MarkReadPayload struct {
+	ConversationID int64 `json:"conversation_id"`
+	Seq            int64 `json:"seq"`
+}
+
+// SyncPayload is the payload of sync
+type SyncPayload struct {
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// ErrorPayload is the payload of error frames
+type ErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewEnvelope encodes payload into a ready to send frame
+func NewEnvelope(eventType, id string, payload interface{}) ([]byte, error) {
+	env := Envelope{
+		Version: ProtocolVersion,
+		Type:    eventType,
+		ID:      id,
+	}
+
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		env.Payload = raw
+	}
+
+	return json.Marshal(env)
+}
+
+// NewErrorEnvelope builds an error frame tied to the correlation id
+func NewErrorEnvelope(id, code, message string) []byte {
+	data, _ := NewEnvelope(EventError, id, ErrorPayload{Code: code, Message: message})
+	return data
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SyncCursor records, per conversation, the last message sequence a device
+// has received
+type SyncCursor map[int64]int64
+
+// Encode turns the cursor into the opaque string handed to clients
+func (c SyncCursor) Encode() string {
+	if len(c) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeSyncCursor parses a cursor produced by SyncCursor.Encode. An empty
+// string is a valid cursor for a device that has never synced.
+func DecodeSyncCursor(s string) (SyncCursor, error) {
+	cursor := SyncCursor{}
+	if s == "" {
+		return cursor, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// PageCursor is a keyset position: the sort timestamp and id of the last
+// row of a page. Ties on the timestamp are broken by the id. Pages that
+// list pinned rows first also keep whether the last row was pinned.
+type PageCursor struct {
+	Time   time.Time `json:"t"`
+	ID     int64     `json:"id"`
+	Pinned bool      `json:"p,omitempty"`
+}
+
+// Encode turns the cursor into the opaque string handed to clients
+func (c PageCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePageCursor parses a cursor produced by PageCursor.Encode. An empty
+// string means "from the start" and yields a nil cursor.
+func DecodePageCursor(s string) (*PageCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor PageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
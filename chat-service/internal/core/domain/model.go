@@ -4,22 +4,247 @@ import (
 	"time"
 )
 
+// Participant roles inside a conversation. 1:1 chats only use RoleMember.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
 type Conversation struct {
 	ID        int64     `json:"id" db:"id"`
 	IsGroup   bool      `json:"is_group" db:"is_group"`
+	Title     *string   `json:"title,omitempty" db:"title"`
+	CreatedBy *int64    `json:"created_by,omitempty" db:"created_by"`
+	LastSeq   int64     `json:"last_seq" db:"last_seq"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
+// InboxEntry is one row of a user's conversation list
+type InboxEntry struct {
+	Conversation
+	LastMessage    *Message      `json:"last_message,omitempty"`
+	Participants   []InboxMember `json:"participants"`
+	UnreadCount    int64         `json:"unread_count"`
+	MutedUntil     *time.Time    `json:"muted_until,omitempty"`
+	Pinned         bool          `json:"pinned"`
+	LastActivityAt time.Time     `json:"last_activity_at"`
+}
+
+// InboxMember is another participant as shown in the conversation list.
+// The profile fields are filled from the user service when it is reachable.
+type InboxMember struct {
+	UserID      int64  `json:"user_id"`
+	Role        string `json:"role"`
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+// UserProfile is the public profile of a user, owned by the user service
+type UserProfile struct {
+	ID          int64  `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+// InboxPage is a page of the conversation list, newest activity first
+type InboxPage struct {
+	Conversations []InboxEntry `json:"conversations"`
+	NextCursor    string       `json:"next_cursor,omitempty"`
+}
+
 type Participant struct {
 	ConversationID int64     `json:"conversation_id" db:"conversation_id"`
 	UserID         int64     `json:"user_id" db:"user_id"`
+	Role           string    `json:"role" db:"role"`
+	DeliveredSeq   int64     `json:"delivered_seq" db:"delivered_seq"`
+	ReadSeq        int64     `json:"read_seq" db:"read_seq"`
 	JoinedAt       time.Time `json:"joined_at" db:"joined_at"`
 }
 
+// Receipt kinds
+const (
+	ReceiptDelivered = "delivered"
+	ReceiptRead      = "read"
+)
+
+// Receipt announces that a participant's delivered or read watermark moved
+type Receipt struct {
+	ConversationID int64 `json:"conversation_id"`
+	UserID         int64 `json:"user_id"`
+	DeliveredSeq   int64 `json:"delivered_seq"`
+	ReadSeq        int64 `json:"read_seq"`
+}
+
 type Message struct {
+	ID             int64      `json:"id" db:"id"`
+	ConversationID int64      `json:"conversation_id" db:"conversation_id"`
+	SenderID       int64      `json:"sender_id" db:"sender_id"`
+	Content        string     `json:"content" db:"content"`
+	Seq            int64      `json:"seq" db:"seq"` // per-conversation, increases by one per message
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	EditedAt       *time.Time `json:"edited_at,omitempty" db:"edited_at"`     // nil until the first edit
+	DeletedAt      *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`   // set on tombstones, whose content is empty
+	ReplyToID      *int64     `json:"reply_to_id,omitempty" db:"reply_to_id"` // inline quote of another message
+	ThreadID       *int64     `json:"thread_id,omitempty" db:"thread_id"`     // root message of the thread this reply belongs to
+	ReplyCount     int64      `json:"reply_count,omitempty" db:"reply_count"` // thread replies, on root messages only
+	// ClientMessageID is the sender's own id for the message, unique per
+	// sender and conversation, so retried sends are not stored twice
+	ClientMessageID *string `json:"client_message_id,omitempty" db:"client_message_id"`
+	// Reactions and Attachments are filled on history pages and when sending
+	Reactions   []ReactionCount `json:"reactions,omitempty" db:"-"`
+	Attachments []Attachment    `json:"attachments,omitempty" db:"-"`
+}
+
+// Attachment is an uploaded file. ConversationID and MessageID are set
+// once it has been sent in a message. Checksum is the hex SHA-256 of the
+// content.
+type Attachment struct {
 	ID             int64     `json:"id" db:"id"`
-	ConversationID int64     `json:"conversation_id" db:"conversation_id"`
-	SenderID       int64     `json:"sender_id" db:"sender_id"`
-	Content        string    `json:"content" db:"content"`
+	UploaderID     int64     `json:"uploader_id" db:"uploader_id"`
+	ConversationID *int64    `json:"conversation_id,omitempty" db:"conversation_id"`
+	MessageID      *int64    `json:"message_id,omitempty" db:"message_id"`
+	FileName       string    `json:"file_name" db:"file_name"`
+	MimeType       string    `json:"mime_type" db:"mime_type"`
+	Size           int64     `json:"size" db:"size"`
+	Checksum       string    `json:"checksum" db:"checksum"`
+	StorageKey     string    `json:"-" db:"storage_key"`
+	ThumbnailKey   *string   `json:"-" db:"thumbnail_key"`
+	HasThumbnail   bool      `json:"has_thumbnail" db:"-"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 }
+
+// Reaction is one user's emoji on a message
+type Reaction struct {
+	ConversationID int64     `json:"conversation_id"`
+	MessageID      int64     `json:"message_id"`
+	UserID         int64     `json:"user_id"`
+	Emoji          string    `json:"emoji"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ReactionCount aggregates the reactions of a message by emoji. Reacted
+// tells whether the user reading the page is among them.
+type ReactionCount struct {
+	MessageID int64  `json:"-" db:"message_id"`
+	Emoji     string `json:"emoji" db:"emoji"`
+	Count     int64  `json:"count" db:"count"`
+	Reacted   bool   `json:"reacted" db:"reacted"`
+}
+
+// Deletion modes
+const (
+	DeleteForMe       = "me"
+	DeleteForEveryone = "everyone"
+)
+
+// MessageDeletion announces that a message was deleted. Deletions for
+// everyone go to all participants, deletions for me to the caller's
+// other devices only.
+type MessageDeletion struct {
+	ConversationID int64     `json:"conversation_id"`
+	MessageID      int64     `json:"message_id"`
+	Mode           string    `json:"mode"`
+	DeletedAt      time.Time `json:"deleted_at"`
+}
+
+// MessageRevision is a version of a message that was replaced by an edit
+type MessageRevision struct {
+	ID        int64     `json:"id" db:"id"`
+	MessageID int64     `json:"message_id" db:"message_id"`
+	Content   string    `json:"content" db:"content"`
+	WrittenAt time.Time `json:"written_at" db:"written_at"`
+}
+
+// Direction is the way a history page walks through a conversation
+type Direction string
+
+const (
+	DirectionBackward Direction = "backward" // towards older messages, newest first
+	DirectionForward  Direction = "forward"  // towards newer messages, oldest first
+)
+
+// HistoryQuery selects a page of a conversation's history. Without a
+// cursor a backward page starts at the newest message and a forward page
+// at the oldest one.
+type HistoryQuery struct {
+	ViewerID  int64  // messages the viewer deleted for themselves are left out
+	ThreadID  *int64 // nil pages the main conversation, otherwise one thread
+	Cursor    *PageCursor
+	Direction Direction
+	Limit     int
+}
+
+// MessagePage is a page of history in the order it was walked.
+// NextCursor continues in the same direction and is empty on the last
+// page; PrevCursor walks back the other way.
+type MessagePage struct {
+	Messages   []Message `json:"messages"`
+	Direction  Direction `json:"direction"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
+}
+
+// SearchQuery is a full-text search over the conversations ViewerID
+// participates in. The optional filters narrow it down; From and To bound
+// the message creation time (inclusive, exclusive).
+type SearchQuery struct {
+	ViewerID       int64
+	Text           string
+	ConversationID *int64
+	SenderID       *int64
+	From           *time.Time
+	To             *time.Time
+	Cursor         *PageCursor
+	Limit          int
+}
+
+// SearchHit is a message matching a search. Snippet is an HTML-escaped
+// excerpt of its content with the matched words wrapped in <mark> tags.
+type SearchHit struct {
+	Message
+	Snippet string `json:"snippet" db:"snippet"`
+}
+
+// SearchPage is a page of search hits, newest first. NextCursor is empty
+// on the last page.
+type SearchPage struct {
+	Hits       []SearchHit `json:"hits"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// PresenceStatus is a user's availability as shown to their peers. The
+// values match the users.status column of the user service.
+type PresenceStatus string
+
+const (
+	PresenceOnline  PresenceStatus = "online"
+	PresenceAway    PresenceStatus = "away" // connected but idle
+	PresenceOffline PresenceStatus = "offline"
+)
+
+// Presence is a user's current status. LastSeenAt is their latest
+// activity, nil if they have not been seen recently.
+type Presence struct {
+	UserID     int64          `json:"user_id"`
+	Status     PresenceStatus `json:"status"`
+	LastSeenAt *time.Time     `json:"last_seen_at,omitempty"`
+}
+
+// SyncResult is what a device receives when catching up after being offline
+type SyncResult struct {
+	Messages []Message `json:"messages"`
+	Cursor   string    `json:"cursor"`
+	HasMore  bool      `json:"has_more"` // sync again with Cursor to get the rest
+}
+
+// IsValidRole reports whether role is one of the known participant roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleOwner, RoleAdmin, RoleMember:
+		return true
+	}
+	return false
+}
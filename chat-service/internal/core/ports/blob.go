@@ -0,0 +1,18 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrBlobNotFound is returned by BlobStore.Get for unknown keys
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps attachment content. Keys are slash separated paths
+// chosen by the chat service.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
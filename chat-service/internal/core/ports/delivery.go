@@ -0,0 +1,9 @@
+package ports
+
+import "context"
+
+// DeliveryBus carries ready to send WebSocket frames to the open
+// connections of the given users, on whichever instance they are held.
+type DeliveryBus interface {
+	Publish(ctx context.Context, userIDs []int64, payload []byte) error
+}
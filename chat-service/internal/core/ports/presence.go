@@ -0,0 +1,41 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+// PresenceState is what a PresenceStore knows about one user
+type PresenceState struct {
+	Connected    bool      // at least one connection heartbeat has not expired
+	LastActiveAt time.Time // zero if the user has not been active recently
+}
+
+// PresenceStore keeps connection heartbeats and announced statuses, shared
+// by every chat-service instance
+type PresenceStore interface {
+	// Heartbeat keeps connection connID of userID alive for ttl. active
+	// also records user activity, which keeps them from going away.
+	Heartbeat(ctx context.Context, userID int64, connID string, ttl time.Duration, active bool) error
+	// Disconnect drops a connection without waiting for it to expire
+	Disconnect(ctx context.Context, userID int64, connID string) error
+	// States returns the state of every listed user
+	States(ctx context.Context, userIDs []int64) (map[int64]PresenceState, error)
+	// UpdateStatus reads the state of userID, records the status derive
+	// makes of it as announced and returns the state with the previously
+	// announced status, offline if there was none. The read and the update
+	// are atomic, so when instances refresh a user at once only one of them
+	// sees a given change.
+	UpdateStatus(ctx context.Context, userID int64, derive func(PresenceState) domain.PresenceStatus) (PresenceState, domain.PresenceStatus, error)
+	// Announced lists the users whose announced status is not offline
+	Announced(ctx context.Context) ([]int64, error)
+}
+
+// PresenceSink is told about every announced status change, so the user
+// service can persist status and last seen. Changes must survive the user
+// service being down.
+type PresenceSink interface {
+	PresenceChanged(ctx context.Context, presence domain.Presence) error
+}
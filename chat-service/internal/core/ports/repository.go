@@ -2,22 +2,92 @@ package ports
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
 )
 
+// ErrDuplicateMessage is returned by SaveMessage when the sender already
+// stored a message with the same client message id in the conversation
+var ErrDuplicateMessage = errors.New("duplicate client message id")
+
 type ChatRepository interface {
+	// WithinTransaction runs fn as one unit of work: everything fn does
+	// through repo is committed together if it returns nil and rolled back
+	// otherwise. Nested calls join the outer transaction.
+	WithinTransaction(ctx context.Context, fn func(repo ChatRepository) error) error
+
 	// Conversation methods
 	CreateConversation(ctx context.Context, conv *domain.Conversation) error
+	// CreateOneToOneConversation creates the conversation between two users
+	// unless they already have one, in which case it returns false and
+	// leaves conv untouched. Participants are added separately.
+	CreateOneToOneConversation(ctx context.Context, conv *domain.Conversation, user1, user2 int64) (bool, error)
 	GetConversationByID(ctx context.Context, id int64) (*domain.Conversation, error)
 
-	// This is crucial for 1:1 chat: Find if a chat already exists between two users
+	// GetInbox lists userID's conversations by latest activity, starting
+	// after the cursor when one is given
+	GetInbox(ctx context.Context, userID int64, after *domain.PageCursor, limit int) ([]domain.InboxEntry, error)
+
+	// This is crucial for 1:1 chat: Find if a chat already exists between two users.
+	// The argument order does not matter.
 	FindOneToOneConversation(ctx context.Context, user1, user2 int64) (*domain.Conversation, error)
 
 	// Participant methods
 	AddParticipant(ctx context.Context, part *domain.Participant) error
+	GetParticipant(ctx context.Context, conversationID, userID int64) (*domain.Participant, error)
+	GetParticipants(ctx context.Context, conversationID int64) ([]domain.Participant, error)
+	// GetPeers lists every user sharing at least one conversation with userID
+	GetPeers(ctx context.Context, userID int64) ([]int64, error)
+	GetParticipantsByConversations(ctx context.Context, conversationIDs []int64) ([]domain.Participant, error)
+	UpdateParticipantSettings(ctx context.Context, conversationID, userID int64, pinned bool, mutedUntil *time.Time) error
+	RemoveParticipant(ctx context.Context, conversationID, userID int64) error
+	UpdateParticipantRole(ctx context.Context, conversationID, userID int64, role string) error
+	// UpdateWatermarks moves the delivered/read watermarks forward, never
+	// back and never past the conversation's last seq. It returns nil if
+	// nothing changed.
+	UpdateWatermarks(ctx context.Context, conversationID, userID, deliveredSeq, readSeq int64) (*domain.Receipt, error)
 
 	// Message methods
 	SaveMessage(ctx context.Context, msg *domain.Message) error
-	GetMessages(ctx context.Context, conversationID int64, limit, offset int) ([]domain.Message, error)
+	GetMessageByID(ctx context.Context, id int64) (*domain.Message, error)
+	// GetMessageByClientID finds a message by its sender's client message
+	// id, nil if there is none
+	GetMessageByClientID(ctx context.Context, conversationID, senderID int64, clientMessageID string) (*domain.Message, error)
+	// UpdateMessageContent replaces a message's content, keeping the
+	// replaced version as a revision, and returns the updated message. It
+	// returns nil for a message that is gone or deleted for everyone.
+	UpdateMessageContent(ctx context.Context, messageID int64, content string, editedAt time.Time) (*domain.Message, error)
+	GetMessageRevisions(ctx context.Context, messageID int64) ([]domain.MessageRevision, error)
+	// HideMessage deletes a message for one user only
+	HideMessage(ctx context.Context, messageID, userID int64, hiddenAt time.Time) error
+	// TombstoneMessage deletes a message for everyone by clearing its
+	// content and its revisions. It returns nil if it was already deleted.
+	TombstoneMessage(ctx context.Context, messageID int64, deletedAt time.Time) (*domain.Message, error)
+	// DeleteMessageAttachments removes the attachment rows of a message and
+	// returns them, so their blobs can be deleted
+	DeleteMessageAttachments(ctx context.Context, messageID int64) ([]domain.Attachment, error)
+	// AddReaction and RemoveReaction report whether anything changed
+	AddReaction(ctx context.Context, reaction *domain.Reaction) (bool, error)
+	RemoveReaction(ctx context.Context, messageID, userID int64, emoji string) (bool, error)
+	// GetMessages returns up to query.Limit messages of a conversation or
+	// of one of its threads, strictly past the cursor in the query's direction
+	GetMessages(ctx context.Context, conversationID int64, query domain.HistoryQuery) ([]domain.Message, error)
+	SaveAttachment(ctx context.Context, attachment *domain.Attachment) error
+	GetAttachment(ctx context.Context, id int64) (*domain.Attachment, error)
+	GetAttachments(ctx context.Context, ids []int64) ([]domain.Attachment, error)
+	// LinkAttachments ties uploaderID's unsent attachments to a message and
+	// returns how many were linked
+	LinkAttachments(ctx context.Context, messageID, conversationID, uploaderID int64, ids []int64) (int64, error)
+	// GetThreadParticipants lists everyone who wrote the root message of a
+	// thread or replied in it
+	GetThreadParticipants(ctx context.Context, rootID int64) ([]int64, error)
+	// SearchMessages runs a full-text search, newest hits first, skipping
+	// tombstones and messages the viewer deleted for themselves
+	SearchMessages(ctx context.Context, query domain.SearchQuery) ([]domain.SearchHit, error)
+	// GetMessagesSince returns, for every conversation of userID, up to limit
+	// messages with a seq above the cursor. Conversations missing from the
+	// cursor start from their latest limit messages.
+	GetMessagesSince(ctx context.Context, userID int64, cursor domain.SyncCursor, limit int) ([]domain.Message, error)
 }
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+// UserDirectory looks users up in the user service. Deleted users do not
+// exist as far as it is concerned.
+type UserDirectory interface {
+	// UserExists reports whether userID belongs to an existing user
+	UserExists(ctx context.Context, userID int64) (bool, error)
+	// GetUsers returns the profiles of the listed users that exist, keyed
+	// by user id
+	GetUsers(ctx context.Context, userIDs []int64) (map[int64]domain.UserProfile, error)
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+// defaultMaxAttachmentSize caps uploads unless configured otherwise
+const defaultMaxAttachmentSize = 25 << 20
+
+// SetBlobStore enables attachments, stored in store
+func (s *ChatService) SetBlobStore(store ports.BlobStore) {
+	s.blobs = store
+}
+
+// SetMaxAttachmentSize changes the largest accepted upload, in bytes
+func (s *ChatService) SetMaxAttachmentSize(size int64) {
+	if size <= 0 {
+		return
+	}
+	s.maxAttachmentSize = size
+}
+
+// UploadAttachment stores a file for userID and returns its attachment,
+// ready to be sent in a message. The MIME type is sniffed from the content;
+// the declared one is only used when sniffing finds nothing specific.
+// Images get a thumbnail.
+func (s *ChatService) UploadAttachment(ctx context.Context, userID int64, fileName, declaredType string, r io.Reader) (*domain.Attachment, error) {
+	if s.blobs == nil {
+		return nil, ErrStorageUnavailable
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.maxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxAttachmentSize {
+		return nil, ErrAttachmentTooLarge
+	}
+	if len(data) == 0 {
+		return nil, ErrInvalidAttachment
+	}
+
+	mimeType := http.DetectContentType(data)
+	if mimeType == "application/octet-stream" && declaredType != "" {
+		mimeType = declaredType
+	}
+	sum := sha256.Sum256(data)
+
+	attachment := &domain.Attachment{
+		UploaderID: userID,
+		FileName:   fileName,
+		MimeType:   mimeType,
+		Size:       int64(len(data)),
+		Checksum:   hex.EncodeToString(sum[:]),
+		StorageKey: "attachments/" + uuid.NewString(),
+		CreatedAt:  time.Now(),
+	}
+	if err := s.blobs.Put(ctx, attachment.StorageKey, bytes.NewReader(data), attachment.Size, mimeType); err != nil {
+		return nil, err
+	}
+
+	if thumb, err := makeThumbnail(mimeType, data); err != nil {
+		// Not fatal, the image is still downloadable
+		log.Printf("Thumbnail for %s failed: %v", attachment.StorageKey, err)
+	} else if thumb != nil {
+		key := "thumbnails/" + uuid.NewString() + ".jpg"
+		if err := s.blobs.Put(ctx, key, bytes.NewReader(thumb), int64(len(thumb)), thumbnailMimeType); err != nil {
+			log.Printf("Storing thumbnail for %s failed: %v", attachment.StorageKey, err)
+		} else {
+			attachment.ThumbnailKey = &key
+			attachment.HasThumbnail = true
+		}
+	}
+
+	if err := s.repo.SaveAttachment(ctx, attachment); err != nil {
+		s.deleteBlobs(ctx, attachment)
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// OpenAttachment returns an attachment and its content, or its thumbnail.
+// Sent attachments are readable by the conversation's participants, unsent
+// ones by their uploader only, and none once their message is deleted for
+// everyone.
+func (s *ChatService) OpenAttachment(ctx context.Context, userID, attachmentID int64, thumbnail bool) (*domain.Attachment, io.ReadCloser, error) {
+	if s.blobs == nil {
+		return nil, nil, ErrStorageUnavailable
+	}
+
+	attachment, err := s.repo.GetAttachment(ctx, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment == nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+
+	if attachment.ConversationID == nil {
+		if attachment.UploaderID != userID {
+			return nil, nil, ErrAttachmentNotFound
+		}
+	} else if _, err := s.authorize(ctx, userID, *attachment.ConversationID, ActionReadHistory); err != nil {
+		return nil, nil, err
+	}
+
+	if attachment.MessageID != nil {
+		msg, err := s.repo.GetMessageByID(ctx, *attachment.MessageID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if msg == nil || msg.DeletedAt != nil {
+			return nil, nil, ErrAttachmentNotFound
+		}
+	}
+
+	key := attachment.StorageKey
+	if thumbnail {
+		if attachment.ThumbnailKey == nil {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		key = *attachment.ThumbnailKey
+	}
+
+	content, err := s.blobs.Get(ctx, key)
+	if err == ports.ErrBlobNotFound {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// pendingAttachments loads the attachments a sender wants to send and
+// makes sure they are theirs and not sent yet
+func (s *ChatService) pendingAttachments(ctx context.Context, senderID int64, ids []int64) ([]domain.Attachment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	attachments, err := s.repo.GetAttachments(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) != len(ids) {
+		return nil, ErrInvalidAttachment
+	}
+	for _, a := range attachments {
+		if a.UploaderID != senderID || a.MessageID != nil {
+			return nil, ErrInvalidAttachment
+		}
+	}
+	return attachments, nil
+}
+
+// linkAttachments ties the attachments to a freshly saved message
+func linkAttachments(ctx context.Context, repo ports.ChatRepository, msg *domain.Message, attachments []domain.Attachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(attachments))
+	for i := range attachments {
+		ids[i] = attachments[i].ID
+		attachments[i].ConversationID = &msg.ConversationID
+		attachments[i].MessageID = &msg.ID
+	}
+	linked, err := repo.LinkAttachments(ctx, msg.ID, msg.ConversationID, msg.SenderID, ids)
+	if err != nil {
+		return err
+	}
+	if linked != int64(len(ids)) {
+		// Another message claimed some of them in the meantime
+		return ErrInvalidAttachment
+	}
+
+	msg.Attachments = attachments
+	return nil
+}
+
+func (s *ChatService) deleteBlobs(ctx context.Context, attachment *domain.Attachment) {
+	s.blobs.Delete(ctx, attachment.StorageKey)
+	if attachment.ThumbnailKey != nil {
+		s.blobs.Delete(ctx, *attachment.ThumbnailKey)
+	}
+}
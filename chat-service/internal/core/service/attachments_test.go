@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+// memoryBlobs is an in-memory BlobStore
+type memoryBlobs struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func newMemoryBlobs() *memoryBlobs {
+	return &memoryBlobs{blobs: make(map[string][]byte)}
+}
+
+func (b *memoryBlobs) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.blobs[key] = data
+	return nil
+}
+
+func (b *memoryBlobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.blobs[key]
+	if !ok {
+		return nil, ports.ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *memoryBlobs) Delete(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.blobs, key)
+	return nil
+}
+
+func TestUploadAttachment_ImageGetsThumbnail(t *testing.T) {
+	repo := new(MockRepo)
+	blobs := newMemoryBlobs()
+	svc := NewChatService(repo, &recordingBus{})
+	svc.SetBlobStore(blobs)
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 800, 400)))
+
+	repo.On("SaveAttachment", mock.Anything, mock.Anything).Return(nil)
+
+	attachment, err := svc.UploadAttachment(context.Background(), 1, "cat.png", "application/octet-stream", &buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", attachment.MimeType)
+	assert.Len(t, attachment.Checksum, 64)
+	assert.True(t, attachment.HasThumbnail)
+	assert.Len(t, blobs.blobs, 2)
+
+	thumb, _, err := image.Decode(bytes.NewReader(blobs.blobs[*attachment.ThumbnailKey]))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 320, 160), thumb.Bounds())
+}
+
+func TestUploadAttachment_RejectsOversizedFiles(t *testing.T) {
+	repo := new(MockRepo)
+	blobs := newMemoryBlobs()
+	svc := NewChatService(repo, &recordingBus{})
+	svc.SetBlobStore(blobs)
+	svc.SetMaxAttachmentSize(10)
+
+	_, err := svc.UploadAttachment(context.Background(), 1, "big.txt", "text/plain", strings.NewReader("more than ten bytes"))
+
+	assert.ErrorIs(t, err, ErrAttachmentTooLarge)
+	assert.Empty(t, blobs.blobs)
+	repo.AssertNotCalled(t, "SaveAttachment", mock.Anything, mock.Anything)
+}
+
+func TestOpenAttachment_OutsiderIsForbidden(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+	svc.SetBlobStore(newMemoryBlobs())
+
+	convID, msgID := int64(10), int64(100)
+	repo.On("GetAttachment", mock.Anything, int64(7)).
+		Return(&domain.Attachment{ID: 7, UploaderID: 1, ConversationID: &convID, MessageID: &msgID}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(9)).Return(nil, nil)
+
+	_, _, err := svc.OpenAttachment(context.Background(), 9, 7, false)
+
+	var forbidden *ForbiddenError
+	assert.ErrorAs(t, err, &forbidden)
+}
+
+func TestSendGroupMessage_RejectsSomeoneElsesAttachment(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("GetConversationByID", mock.Anything, int64(10)).
+		Return(&domain.Conversation{ID: 10, IsGroup: true}, nil)
+	repo.On("GetAttachments", mock.Anything, []int64{7}).
+		Return([]domain.Attachment{{ID: 7, UploaderID: 1}}, nil)
+
+	_, err := svc.SendGroupMessage(context.Background(), 2, 10, "look", "", 7)
+
+	assert.ErrorIs(t, err, ErrInvalidAttachment)
+	repo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
+
+func TestSendGroupMessage_LinksAttachments(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("GetConversationByID", mock.Anything, int64(10)).
+		Return(&domain.Conversation{ID: 10, IsGroup: true}, nil)
+	repo.On("GetAttachments", mock.Anything, []int64{7}).
+		Return([]domain.Attachment{{ID: 7, UploaderID: 2}}, nil)
+	repo.On("SaveMessage", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.Message).ID = 100
+	}).Return(nil)
+	repo.On("LinkAttachments", mock.Anything, int64(100), int64(10), int64(2), []int64{7}).Return(int64(1), nil)
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{{UserID: 1}, {UserID: 2}}, nil)
+
+	msg, err := svc.SendGroupMessage(context.Background(), 2, 10, "look", "", 7)
+
+	assert.NoError(t, err)
+	assert.Len(t, msg.Attachments, 1)
+	assert.Equal(t, int64(100), *msg.Attachments[0].MessageID)
+	repo.AssertExpectations(t)
+}
+
+func TestDeleteMessage_ForEveryoneRemovesAttachments(t *testing.T) {
+	repo := new(MockRepo)
+	blobs := newMemoryBlobs()
+	svc := NewChatService(repo, &recordingBus{})
+	svc.SetBlobStore(blobs)
+
+	thumbKey := "thumbnails/1.jpg"
+	blobs.blobs["attachments/1"] = []byte("file")
+	blobs.blobs[thumbKey] = []byte("thumb")
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleMember}, nil)
+	repo.On("TombstoneMessage", mock.Anything, int64(100), mock.AnythingOfType("time.Time")).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("DeleteMessageAttachments", mock.Anything, int64(100)).
+		Return([]domain.Attachment{{ID: 7, StorageKey: "attachments/1", ThumbnailKey: &thumbKey}}, nil)
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{{UserID: 1}, {UserID: 2}}, nil)
+
+	_, err := svc.DeleteMessage(context.Background(), 1, 100, domain.DeleteForEveryone)
+
+	assert.NoError(t, err)
+	assert.Empty(t, blobs.blobs)
+	repo.AssertExpectations(t)
+}
+
+func TestOpenAttachment_NotFoundOnceMessageDeleted(t *testing.T) {
+	repo := new(MockRepo)
+	blobs := newMemoryBlobs()
+	svc := NewChatService(repo, &recordingBus{})
+	svc.SetBlobStore(blobs)
+	blobs.blobs["attachments/1"] = []byte("file")
+
+	convID, msgID := int64(10), int64(100)
+	deletedAt := time.Now()
+	repo.On("GetAttachment", mock.Anything, int64(7)).
+		Return(&domain.Attachment{ID: 7, UploaderID: 1, ConversationID: &convID, MessageID: &msgID, StorageKey: "attachments/1"}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1, DeletedAt: &deletedAt}, nil)
+
+	_, _, err := svc.OpenAttachment(context.Background(), 2, 7, false)
+
+	assert.ErrorIs(t, err, ErrAttachmentNotFound)
+}
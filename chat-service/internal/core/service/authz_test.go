@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+func TestGetHistory_OutsiderIsForbidden(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(9)).Return(nil, nil)
+
+	page, err := svc.GetHistory(context.Background(), 9, 10, "", "", 0)
+
+	assert.Nil(t, page)
+	var forbidden *ForbiddenError
+	assert.True(t, errors.As(err, &forbidden))
+	assert.Equal(t, ActionReadHistory, forbidden.Action)
+	assert.ErrorIs(t, err, ErrNotParticipant)
+	repo.AssertNotCalled(t, "GetMessages", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAddMembers_MemberIsForbidden(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+
+	added, err := svc.AddMembers(context.Background(), 2, 10, []int64{3})
+
+	assert.Nil(t, added)
+	var forbidden *ForbiddenError
+	assert.True(t, errors.As(err, &forbidden))
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	repo.AssertNotCalled(t, "AddParticipant", mock.Anything, mock.Anything)
+}
+
+func TestChangeRole_AdminIsForbidden(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleAdmin}, nil)
+
+	err := svc.ChangeRole(context.Background(), 2, 10, 3, domain.RoleAdmin)
+
+	var forbidden *ForbiddenError
+	assert.True(t, errors.As(err, &forbidden))
+	assert.Equal(t, ActionChangeRoles, forbidden.Action)
+	repo.AssertNotCalled(t, "UpdateParticipantRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMarkRead_OutsiderIsForbidden(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(9)).Return(nil, nil)
+
+	receipt, err := svc.MarkRead(context.Background(), 9, 10, 5)
+
+	assert.Nil(t, receipt)
+	var forbidden *ForbiddenError
+	assert.True(t, errors.As(err, &forbidden))
+	repo.AssertNotCalled(t, "UpdateWatermarks", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSendGroupMessage_OutsiderCannotProbeConversation(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	// The conversation does not exist, the answer is still forbidden
+	repo.On("GetParticipant", mock.Anything, int64(404), int64(9)).Return(nil, nil)
+
+	_, err := svc.SendGroupMessage(context.Background(), 9, 404, "hi", "")
+
+	var forbidden *ForbiddenError
+	assert.True(t, errors.As(err, &forbidden))
+	repo.AssertNotCalled(t, "GetConversationByID", mock.Anything, mock.Anything)
+}
@@ -2,7 +2,8 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"log"
 	"time"
 
 	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
@@ -11,75 +12,176 @@ import (
 	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
 )
 
+var (
+	ErrConversationNotFound   = errors.New("conversation not found")
+	ErrNotGroupConversation   = errors.New("conversation is not a group")
+	ErrNotParticipant         = errors.New("user is not a participant of this conversation")
+	ErrPermissionDenied       = errors.New("permission denied")
+	ErrInvalidRole            = errors.New("invalid role")
+	ErrEmptyTitle             = errors.New("group title is required")
+	ErrMessageNotFound        = errors.New("message not found")
+	ErrInvalidReceipt         = errors.New("receipt type must be delivered or read")
+	ErrInvalidDirection       = errors.New("direction must be backward or forward")
+	ErrEmptyContent           = errors.New("message content is required")
+	ErrEditWindowExpired      = errors.New("message can no longer be edited")
+	ErrInvalidDeleteMode      = errors.New("delete mode must be me or everyone")
+	ErrReactionNotAllowed     = errors.New("reaction is not allowed")
+	ErrStorageUnavailable     = errors.New("attachment storage is not configured")
+	ErrAttachmentTooLarge     = errors.New("attachment is too large")
+	ErrInvalidAttachment      = errors.New("invalid attachment")
+	ErrAttachmentNotFound     = errors.New("attachment not found")
+	ErrEmptySearch            = errors.New("search query is required")
+	ErrInvalidDateRange       = errors.New("from must be before to")
+	ErrInvalidClientMessageID = errors.New("client message id is too long")
+	ErrPresenceUnavailable    = errors.New("presence is not enabled")
+	ErrTooManyUsers           = errors.New("too many users requested")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrUserLookupFailed       = errors.New("user service is unavailable")
+)
+
 type ChatService struct {
-	repo      ports.ChatRepository
-	wsManager *websocket.ClientManager
+	repo       ports.ChatRepository
+	bus        ports.DeliveryBus
+	typing     *typingTracker
+	editWindow time.Duration
+	reactions  map[string]bool
+
+	blobs             ports.BlobStore
+	maxAttachmentSize int64
+
+	presence     ports.PresenceStore
+	presenceSink ports.PresenceSink
+	presenceTTL  time.Duration
+	awayAfter    time.Duration
+
+	users ports.UserDirectory
 }
 
-func NewChatService(repo ports.ChatRepository, wsManager *websocket.ClientManager) *ChatService {
-	return &ChatService{
-		repo:      repo,
-		wsManager: wsManager,
+func NewChatService(repo ports.ChatRepository, bus ports.DeliveryBus) *ChatService {
+	s := &ChatService{
+		repo:       repo,
+		bus:        bus,
+		typing:     newTypingTracker(defaultTypingTimeout),
+		editWindow: defaultEditWindow,
+
+		maxAttachmentSize: defaultMaxAttachmentSize,
+
+		presenceTTL: defaultPresenceTTL,
+		awayAfter:   defaultAwayAfter,
 	}
+	s.SetAllowedReactions(defaultReactions)
+	return s
 }
 
 // SendMessage handles the logic:
 // 1. Check if conversation exists (if not, create it)
-// 2. Save message
-func (s *ChatService) SendMessage(ctx context.Context, senderID, recipientID int64, content string) (*domain.Message, error) {
+// 2. Save message, with any attachments the sender uploaded beforehand
+// A retry carrying the clientMessageID of a stored message returns that
+// message and delivers nothing. Messages to users that do not exist, or
+// were deleted, are rejected.
+func (s *ChatService) SendMessage(ctx context.Context, senderID, recipientID int64, content, clientMessageID string, attachmentIDs ...int64) (*domain.Message, error) {
+	if !validClientMessageID(clientMessageID) {
+		return nil, ErrInvalidClientMessageID
+	}
+	if err := s.requireUsers(ctx, recipientID); err != nil {
+		return nil, err
+	}
+
 	// 1. Check for existing conversation
 	conv, err := s.repo.FindOneToOneConversation(ctx, senderID, recipientID)
 	if err != nil {
 		return nil, err
 	}
+	if conv != nil {
+		original, err := s.previousSend(ctx, conv.ID, senderID, clientMessageID)
+		if err != nil {
+			return nil, err
+		}
+		if original != nil {
+			return original, nil
+		}
+	}
+
+	attachments, err := s.pendingAttachments(ctx, senderID, attachmentIDs)
+	if err != nil {
+		return nil, err
+	}
 
 	// 2. If no conversation, create one
 	if conv == nil {
-		newConv := &domain.Conversation{
-			IsGroup:   false,
-			CreatedAt: time.Now(),
-		}
-		// Repo will fill in the ID
-		if err := s.repo.CreateConversation(ctx, newConv); err != nil {
+		if conv, err = s.openOneToOne(ctx, senderID, recipientID); err != nil {
 			return nil, err
 		}
-		conv = newConv
-
-		// Add participants
-		p1 := &domain.Participant{ConversationID: conv.ID, UserID: senderID, JoinedAt: time.Now()}
-		p2 := &domain.Participant{ConversationID: conv.ID, UserID: recipientID, JoinedAt: time.Now()}
-
-		s.repo.AddParticipant(ctx, p1)
-		s.repo.AddParticipant(ctx, p2)
 	}
 
 	// 3. Create the Message object
 	msg := &domain.Message{
-		ConversationID: conv.ID,
-		SenderID:       senderID,
-		Content:        content,
-		CreatedAt:      time.Now(),
+		ConversationID:  conv.ID,
+		SenderID:        senderID,
+		Content:         content,
+		ClientMessageID: clientMessageIDPtr(clientMessageID),
+		CreatedAt:       time.Now(),
 	}
 
 	// 4. Save to DB
-	if err := s.repo.SaveMessage(ctx, msg); err != nil {
-		return nil, err
+	stored, duplicate, err := s.saveMessage(ctx, msg, attachments)
+	if err != nil || duplicate {
+		return stored, err
 	}
 
-	if conn, ok := s.wsManager.GetClient(recipientID); ok {
-		// 2. Marshal message to JSON
-		msgBytes, _ := json.Marshal(msg)
+	s.deliver(ctx, []int64{recipientID}, websocket.EventMessage, msg)
+
+	return msg, nil
+}
 
-		// 3. Send to Recipient
-		if err := conn.WriteMessage(1, msgBytes); err != nil {
-			// If sending fails, maybe they just disconnected
-			s.wsManager.RemoveClient(recipientID)
+// openOneToOne creates the conversation between two users along with both
+// participants, atomically. When both users write first at the same moment,
+// one of them creates it and the other gets the same conversation.
+func (s *ChatService) openOneToOne(ctx context.Context, senderID, recipientID int64) (*domain.Conversation, error) {
+	var conv *domain.Conversation
+	err := s.repo.WithinTransaction(ctx, func(repo ports.ChatRepository) error {
+		newConv := &domain.Conversation{IsGroup: false, CreatedAt: time.Now()}
+		created, err := repo.CreateOneToOneConversation(ctx, newConv, senderID, recipientID)
+		if err != nil {
+			return err
+		}
+		if !created {
+			conv, err = repo.FindOneToOneConversation(ctx, senderID, recipientID)
+			return err
 		}
-	}
 
-	return msg, nil
+		userIDs := []int64{senderID}
+		if recipientID != senderID {
+			userIDs = append(userIDs, recipientID)
+		}
+		for _, userID := range userIDs {
+			part := &domain.Participant{ConversationID: newConv.ID, UserID: userID, JoinedAt: time.Now()}
+			if err := repo.AddParticipant(ctx, part); err != nil {
+				return err
+			}
+		}
+		conv = newConv
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conv, nil
 }
 
-func (s *ChatService) GetHistory(ctx context.Context, conversationID int64) ([]domain.Message, error) {
-	return s.repo.GetMessages(ctx, conversationID, 50, 0) // Limit 50 for now
+// deliver wraps payload into an event envelope and publishes it on the
+// delivery bus for every listed user
+func (s *ChatService) deliver(ctx context.Context, userIDs []int64, eventType string, payload interface{}) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	msgBytes, err := websocket.NewEnvelope(eventType, "", payload)
+	if err != nil {
+		return
+	}
+
+	if err := s.bus.Publish(ctx, userIDs, msgBytes); err != nil {
+		log.Printf("Failed to publish %s event: %v", eventType, err)
+	}
 }
@@ -11,9 +11,15 @@ import (
 
 	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
 	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
 )
 
-type MockRepo struct{ mock.Mock }
+type MockRepo struct {
+	mock.Mock
+	// inTransaction is set while WithinTransaction runs, so tests can
+	// check which writes are grouped
+	inTransaction bool
+}
 
 func (m *MockRepo) GetConversationByID(ctx context.Context, id int64) (*domain.Conversation, error) {
 	args := m.Called(ctx, id)
@@ -31,6 +37,24 @@ func (m *MockRepo) FindOneToOneConversation(ctx context.Context, s, r int64) (*d
 	return args.Get(0).(*domain.Conversation), args.Error(1)
 }
 
+// WithinTransaction runs fn right away, the mock has nothing to roll back
+func (m *MockRepo) WithinTransaction(ctx context.Context, fn func(repo ports.ChatRepository) error) error {
+	outer := m.inTransaction
+	m.inTransaction = true
+	defer func() { m.inTransaction = outer }()
+	return fn(m)
+}
+
+func (m *MockRepo) CreateOneToOneConversation(ctx context.Context, c *domain.Conversation, user1, user2 int64) (bool, error) {
+	args := m.Called(ctx, c, user1, user2)
+	created := args.Bool(0)
+	// Simulate DB assigning ID
+	if created && c.ID == 0 {
+		c.ID = 777
+	}
+	return created, args.Error(1)
+}
+
 func (m *MockRepo) CreateConversation(ctx context.Context, c *domain.Conversation) error {
 	args := m.Called(ctx, c)
 	// Simulate DB assigning ID
@@ -40,16 +64,161 @@ func (m *MockRepo) CreateConversation(ctx context.Context, c *domain.Conversatio
 	return args.Error(0)
 }
 
+func (m *MockRepo) GetPeers(ctx context.Context, userID int64) ([]int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
 func (m *MockRepo) AddParticipant(ctx context.Context, p *domain.Participant) error {
 	return m.Called(ctx, p).Error(0)
 }
 
+func (m *MockRepo) GetParticipant(ctx context.Context, convID, userID int64) (*domain.Participant, error) {
+	args := m.Called(ctx, convID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Participant), args.Error(1)
+}
+
+func (m *MockRepo) GetParticipants(ctx context.Context, convID int64) ([]domain.Participant, error) {
+	args := m.Called(ctx, convID)
+	return args.Get(0).([]domain.Participant), args.Error(1)
+}
+
+func (m *MockRepo) GetParticipantsByConversations(ctx context.Context, convIDs []int64) ([]domain.Participant, error) {
+	args := m.Called(ctx, convIDs)
+	return args.Get(0).([]domain.Participant), args.Error(1)
+}
+
+func (m *MockRepo) UpdateParticipantSettings(ctx context.Context, convID, userID int64, pinned bool, mutedUntil *time.Time) error {
+	return m.Called(ctx, convID, userID, pinned, mutedUntil).Error(0)
+}
+
+func (m *MockRepo) GetInbox(ctx context.Context, userID int64, after *domain.PageCursor, limit int) ([]domain.InboxEntry, error) {
+	args := m.Called(ctx, userID, after, limit)
+	return args.Get(0).([]domain.InboxEntry), args.Error(1)
+}
+
+func (m *MockRepo) RemoveParticipant(ctx context.Context, convID, userID int64) error {
+	return m.Called(ctx, convID, userID).Error(0)
+}
+
+func (m *MockRepo) UpdateParticipantRole(ctx context.Context, convID, userID int64, role string) error {
+	return m.Called(ctx, convID, userID, role).Error(0)
+}
+
+func (m *MockRepo) UpdateWatermarks(ctx context.Context, convID, userID, delivered, read int64) (*domain.Receipt, error) {
+	args := m.Called(ctx, convID, userID, delivered, read)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Receipt), args.Error(1)
+}
+
+func (m *MockRepo) GetMessageByID(ctx context.Context, id int64) (*domain.Message, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Message), args.Error(1)
+}
+
+func (m *MockRepo) UpdateMessageContent(ctx context.Context, id int64, content string, editedAt time.Time) (*domain.Message, error) {
+	args := m.Called(ctx, id, content, editedAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Message), args.Error(1)
+}
+
+func (m *MockRepo) GetMessageRevisions(ctx context.Context, id int64) ([]domain.MessageRevision, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]domain.MessageRevision), args.Error(1)
+}
+
+func (m *MockRepo) HideMessage(ctx context.Context, id, userID int64, hiddenAt time.Time) error {
+	return m.Called(ctx, id, userID, hiddenAt).Error(0)
+}
+
+func (m *MockRepo) TombstoneMessage(ctx context.Context, id int64, deletedAt time.Time) (*domain.Message, error) {
+	args := m.Called(ctx, id, deletedAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Message), args.Error(1)
+}
+
+func (m *MockRepo) DeleteMessageAttachments(ctx context.Context, id int64) ([]domain.Attachment, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]domain.Attachment), args.Error(1)
+}
+
+func (m *MockRepo) AddReaction(ctx context.Context, reaction *domain.Reaction) (bool, error) {
+	args := m.Called(ctx, reaction)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) RemoveReaction(ctx context.Context, id, userID int64, emoji string) (bool, error) {
+	args := m.Called(ctx, id, userID, emoji)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) SaveAttachment(ctx context.Context, attachment *domain.Attachment) error {
+	args := m.Called(ctx, attachment)
+	if attachment.ID == 0 {
+		attachment.ID = 555
+	}
+	return args.Error(0)
+}
+
+func (m *MockRepo) GetAttachment(ctx context.Context, id int64) (*domain.Attachment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Attachment), args.Error(1)
+}
+
+func (m *MockRepo) GetAttachments(ctx context.Context, ids []int64) ([]domain.Attachment, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]domain.Attachment), args.Error(1)
+}
+
+func (m *MockRepo) LinkAttachments(ctx context.Context, messageID, convID, uploaderID int64, ids []int64) (int64, error) {
+	args := m.Called(ctx, messageID, convID, uploaderID, ids)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) GetMessageByClientID(ctx context.Context, convID, senderID int64, clientMessageID string) (*domain.Message, error) {
+	args := m.Called(ctx, convID, senderID, clientMessageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Message), args.Error(1)
+}
+
+func (m *MockRepo) SearchMessages(ctx context.Context, query domain.SearchQuery) ([]domain.SearchHit, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]domain.SearchHit), args.Error(1)
+}
+
 func (m *MockRepo) SaveMessage(ctx context.Context, msg *domain.Message) error {
 	return m.Called(ctx, msg).Error(0)
 }
 
-func (m *MockRepo) GetMessages(ctx context.Context, id int64, limit, offset int) ([]domain.Message, error) {
-	args := m.Called(ctx, id, limit, offset)
+func (m *MockRepo) GetMessages(ctx context.Context, id int64, query domain.HistoryQuery) ([]domain.Message, error) {
+	args := m.Called(ctx, id, query)
+	return args.Get(0).([]domain.Message), args.Error(1)
+}
+
+func (m *MockRepo) GetThreadParticipants(ctx context.Context, rootID int64) ([]int64, error) {
+	args := m.Called(ctx, rootID)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) GetMessagesSince(ctx context.Context, userID int64, cursor domain.SyncCursor, limit int) ([]domain.Message, error) {
+	args := m.Called(ctx, userID, cursor, limit)
 	return args.Get(0).([]domain.Message), args.Error(1)
 }
 
@@ -73,7 +242,7 @@ func TestSendMessage_ExistingConversation(t *testing.T) {
 	repo.On("SaveMessage", mock.Anything, mock.AnythingOfType("*domain.Message")).
 		Return(nil)
 
-	msg, err := svc.SendMessage(ctx, sender, recipient, content)
+	msg, err := svc.SendMessage(ctx, sender, recipient, content, "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, conv.ID, msg.ConversationID)
@@ -97,14 +266,9 @@ func TestSendMessage_NoConversation_CreatesConversation(t *testing.T) {
 	repo.On("FindOneToOneConversation", mock.Anything, sender, recipient).
 		Return((*domain.Conversation)(nil), nil)
 
-	// CreateConversation: context + pointer
-	repo.On("CreateConversation", mock.Anything, mock.AnythingOfType("*domain.Conversation")).
-		Return(nil).
-		Run(func(args mock.Arguments) {
-			conv := args.Get(1).(*domain.Conversation) // now safe: arg1 is the conversation
-			conv.ID = 777
-			conv.CreatedAt = time.Now()
-		})
+	// CreateOneToOneConversation: context + pointer + both users
+	repo.On("CreateOneToOneConversation", mock.Anything, mock.AnythingOfType("*domain.Conversation"), sender, recipient).
+		Return(true, nil)
 
 	// AddParticipant called twice
 	repo.On("AddParticipant", mock.Anything, mock.AnythingOfType("*domain.Participant")).
@@ -114,7 +278,7 @@ func TestSendMessage_NoConversation_CreatesConversation(t *testing.T) {
 	repo.On("SaveMessage", mock.Anything, mock.AnythingOfType("*domain.Message")).
 		Return(nil)
 
-	msg, err := svc.SendMessage(ctx, sender, recipient, content)
+	msg, err := svc.SendMessage(ctx, sender, recipient, content, "")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, msg)
@@ -142,7 +306,7 @@ func TestSendMessage_SaveMessageError(t *testing.T) {
 	repo.On("SaveMessage", mock.Anything, mock.Anything).
 		Return(errors.New("db error"))
 
-	msg, err := svc.SendMessage(ctx, sender, recipient, "x")
+	msg, err := svc.SendMessage(ctx, sender, recipient, "x", "")
 
 	assert.Nil(t, msg)
 	assert.Error(t, err)
@@ -150,3 +314,47 @@ func TestSendMessage_SaveMessageError(t *testing.T) {
 
 	repo.AssertExpectations(t)
 }
+
+func TestSendMessage_ConcurrentFirstMessagesShareConversation(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	winner := &domain.Conversation{ID: 42}
+
+	// Not there when looked up, created by the peer before our insert
+	repo.On("FindOneToOneConversation", mock.Anything, int64(1), int64(2)).
+		Return((*domain.Conversation)(nil), nil).Once()
+	repo.On("CreateOneToOneConversation", mock.Anything, mock.Anything, int64(1), int64(2)).
+		Return(false, nil)
+	repo.On("FindOneToOneConversation", mock.Anything, int64(1), int64(2)).
+		Return(winner, nil).Once()
+	repo.On("SaveMessage", mock.Anything, mock.Anything).Return(nil)
+
+	msg, err := svc.SendMessage(ctx, 1, 2, "hey", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), msg.ConversationID)
+	repo.AssertNotCalled(t, "AddParticipant", mock.Anything, mock.Anything)
+	repo.AssertExpectations(t)
+}
+
+func TestSendMessage_AddParticipantErrorAborts(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("FindOneToOneConversation", mock.Anything, int64(1), int64(2)).
+		Return((*domain.Conversation)(nil), nil)
+	repo.On("CreateOneToOneConversation", mock.Anything, mock.Anything, int64(1), int64(2)).
+		Return(true, nil)
+	repo.On("AddParticipant", mock.Anything, mock.Anything).Return(errors.New("db error"))
+
+	msg, err := svc.SendMessage(ctx, 1, 2, "hey", "")
+
+	assert.Nil(t, msg)
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+// DeleteMessage deletes a message in one of two modes. DeleteForMe hides
+// it from userID's own history. DeleteForEveryone turns it into a
+// tombstone for all participants; authors can do this to their own
+// messages, group owners and admins also to messages of lower-ranked members.
+func (s *ChatService) DeleteMessage(ctx context.Context, userID, messageID int64, mode string) (*domain.MessageDeletion, error) {
+	if mode != domain.DeleteForMe && mode != domain.DeleteForEveryone {
+		return nil, ErrInvalidDeleteMode
+	}
+
+	msg, err := s.repo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, ErrMessageNotFound
+	}
+
+	actor, err := s.authorize(ctx, userID, msg.ConversationID, ActionDeleteMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == domain.DeleteForMe {
+		return s.hideMessage(ctx, userID, msg)
+	}
+
+	if msg.SenderID != userID {
+		if err := s.checkModeration(ctx, actor, msg); err != nil {
+			return nil, err
+		}
+	}
+
+	deletion := &domain.MessageDeletion{
+		ConversationID: msg.ConversationID,
+		MessageID:      msg.ID,
+		Mode:           domain.DeleteForEveryone,
+		DeletedAt:      time.Now(),
+	}
+	// The attachments go with the content; their blobs are deleted once the
+	// tombstone is committed
+	var tombstone *domain.Message
+	err = s.repo.WithinTransaction(ctx, func(repo ports.ChatRepository) error {
+		var err error
+		tombstone, err = repo.TombstoneMessage(ctx, messageID, deletion.DeletedAt)
+		if err != nil || tombstone == nil {
+			return err
+		}
+		tombstone.Attachments, err = repo.DeleteMessageAttachments(ctx, messageID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tombstone == nil {
+		// Already deleted, nobody needs to hear about it again
+		if msg.DeletedAt != nil {
+			deletion.DeletedAt = *msg.DeletedAt
+		}
+		return deletion, nil
+	}
+
+	if s.blobs != nil {
+		for i := range tombstone.Attachments {
+			s.deleteBlobs(ctx, &tombstone.Attachments[i])
+		}
+	}
+
+	parts, err := s.repo.GetParticipants(ctx, msg.ConversationID)
+	if err != nil {
+		return deletion, nil
+	}
+
+	recipients := make([]int64, 0, len(parts))
+	for _, p := range parts {
+		recipients = append(recipients, p.UserID)
+	}
+	s.deliver(ctx, recipients, websocket.EventMessageDeleted, deletion)
+
+	return deletion, nil
+}
+
+func (s *ChatService) hideMessage(ctx context.Context, userID int64, msg *domain.Message) (*domain.MessageDeletion, error) {
+	deletion := &domain.MessageDeletion{
+		ConversationID: msg.ConversationID,
+		MessageID:      msg.ID,
+		Mode:           domain.DeleteForMe,
+		DeletedAt:      time.Now(),
+	}
+	if err := s.repo.HideMessage(ctx, msg.ID, userID, deletion.DeletedAt); err != nil {
+		return nil, err
+	}
+
+	// Only the caller's other devices need to drop the message
+	s.deliver(ctx, []int64{userID}, websocket.EventMessageDeleted, deletion)
+	return deletion, nil
+}
+
+// checkModeration allows actor to delete someone else's message if they
+// manage the group and outrank the author. Authors who left the group no
+// longer have a rank.
+func (s *ChatService) checkModeration(ctx context.Context, actor *domain.Participant, msg *domain.Message) error {
+	if err := checkAccess(actor, actor.UserID, msg.ConversationID, ActionModerate); err != nil {
+		return err
+	}
+
+	author, err := s.repo.GetParticipant(ctx, msg.ConversationID, msg.SenderID)
+	if err != nil {
+		return err
+	}
+	if author != nil && roleRank(author.Role) >= roleRank(actor.Role) {
+		return forbidden(actor.UserID, msg.ConversationID, ActionModerate, ErrPermissionDenied)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+func TestDeleteMessage_ForMeHidesOnlyForCaller(t *testing.T) {
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("HideMessage", mock.Anything, int64(100), int64(2), mock.AnythingOfType("time.Time")).
+		Return(nil)
+
+	deletion, err := svc.DeleteMessage(context.Background(), 2, 100, domain.DeleteForMe)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DeleteForMe, deletion.Mode)
+	assert.Equal(t, []string{websocket.EventMessageDeleted}, bus.Events())
+	repo.AssertNotCalled(t, "TombstoneMessage", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertExpectations(t)
+}
+
+func TestDeleteMessage_AdminDeletesMemberMessageForEveryone(t *testing.T) {
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1, Content: "spam"}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleAdmin}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleMember}, nil)
+	deletedAt := time.Now()
+	repo.On("TombstoneMessage", mock.Anything, int64(100), mock.AnythingOfType("time.Time")).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1, DeletedAt: &deletedAt}, nil)
+	repo.On("DeleteMessageAttachments", mock.Anything, int64(100)).Return([]domain.Attachment(nil), nil)
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{{UserID: 1}, {UserID: 2}, {UserID: 3}}, nil)
+
+	deletion, err := svc.DeleteMessage(context.Background(), 2, 100, domain.DeleteForEveryone)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DeleteForEveryone, deletion.Mode)
+	assert.Equal(t, []string{websocket.EventMessageDeleted}, bus.Events())
+	repo.AssertExpectations(t)
+}
+
+func TestDeleteMessage_MemberCannotDeleteOthersForEveryone(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+
+	_, err := svc.DeleteMessage(context.Background(), 2, 100, domain.DeleteForEveryone)
+
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	repo.AssertNotCalled(t, "TombstoneMessage", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeleteMessage_InvalidMode(t *testing.T) {
+	svc := NewChatService(new(MockRepo), &recordingBus{})
+
+	_, err := svc.DeleteMessage(context.Background(), 1, 100, "everyone-else")
+
+	assert.ErrorIs(t, err, ErrInvalidDeleteMode)
+}
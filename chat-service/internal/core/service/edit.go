@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+// defaultEditWindow is how long after sending a message its author may
+// still edit it
+const defaultEditWindow = 15 * time.Minute
+
+// SetEditWindow changes how long messages stay editable after being sent
+func (s *ChatService) SetEditWindow(window time.Duration) {
+	if window <= 0 {
+		return
+	}
+	s.editWindow = window
+}
+
+// EditMessage replaces the content of one of userID's own messages. The
+// previous content is kept as a revision and every participant gets a
+// message_edited event.
+func (s *ChatService) EditMessage(ctx context.Context, userID, messageID int64, content string) (*domain.Message, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyContent
+	}
+
+	msg, err := s.repo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil || msg.DeletedAt != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if _, err := s.authorize(ctx, userID, msg.ConversationID, ActionEditMessage); err != nil {
+		return nil, err
+	}
+	if msg.SenderID != userID {
+		return nil, forbidden(userID, msg.ConversationID, ActionEditMessage, ErrPermissionDenied)
+	}
+
+	now := time.Now()
+	if now.Sub(msg.CreatedAt) > s.editWindow {
+		return nil, ErrEditWindowExpired
+	}
+	if msg.Content == content {
+		return msg, nil
+	}
+
+	edited, err := s.repo.UpdateMessageContent(ctx, messageID, content, now)
+	if err != nil {
+		return nil, err
+	}
+	if edited == nil {
+		return nil, ErrMessageNotFound
+	}
+
+	parts, err := s.repo.GetParticipants(ctx, edited.ConversationID)
+	if err != nil {
+		// The edit is stored, participants will see it in their history
+		return edited, nil
+	}
+
+	recipients := make([]int64, 0, len(parts))
+	for _, p := range parts {
+		recipients = append(recipients, p.UserID)
+	}
+	s.deliver(ctx, recipients, websocket.EventMessageEdited, edited)
+
+	return edited, nil
+}
+
+// GetMessageRevisions lists the earlier versions of a message, oldest
+// first, to participants of its conversation
+func (s *ChatService) GetMessageRevisions(ctx context.Context, userID, messageID int64) ([]domain.MessageRevision, error) {
+	msg, err := s.repo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if _, err := s.authorize(ctx, userID, msg.ConversationID, ActionReadHistory); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetMessageRevisions(ctx, messageID)
+}
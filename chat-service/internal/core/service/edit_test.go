@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+func TestEditMessage_StoresRevisionAndNotifiesEveryone(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+
+	original := &domain.Message{ID: 100, ConversationID: 10, SenderID: 1, Content: "helo", CreatedAt: time.Now()}
+	editedAt := time.Now()
+	edited := &domain.Message{ID: 100, ConversationID: 10, SenderID: 1, Content: "hello", CreatedAt: original.CreatedAt, EditedAt: &editedAt}
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).Return(original, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleMember}, nil)
+	repo.On("UpdateMessageContent", mock.Anything, int64(100), "hello", mock.AnythingOfType("time.Time")).
+		Return(edited, nil)
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{{UserID: 1}, {UserID: 2}}, nil)
+
+	msg, err := svc.EditMessage(ctx, 1, 100, "hello")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", msg.Content)
+	assert.NotNil(t, msg.EditedAt)
+	assert.Equal(t, []string{websocket.EventMessageEdited}, bus.Events())
+	repo.AssertExpectations(t)
+}
+
+func TestEditMessage_DeletedBeforeTheUpdate(t *testing.T) {
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+
+	// Still there when looked at, deleted for everyone before the update ran
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1, Content: "helo", CreatedAt: time.Now()}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleMember}, nil)
+	repo.On("UpdateMessageContent", mock.Anything, int64(100), "hello", mock.AnythingOfType("time.Time")).
+		Return(nil, nil)
+
+	msg, err := svc.EditMessage(context.Background(), 1, 100, "hello")
+
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+	assert.Nil(t, msg)
+	assert.Empty(t, bus.Events())
+	repo.AssertExpectations(t)
+}
+
+func TestEditMessage_OnlyAuthorCanEdit(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1, Content: "hi", CreatedAt: time.Now()}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleOwner}, nil)
+
+	_, err := svc.EditMessage(context.Background(), 2, 100, "hijacked")
+
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	repo.AssertNotCalled(t, "UpdateMessageContent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEditMessage_WindowExpired(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+	svc.SetEditWindow(time.Minute)
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1, Content: "hi", CreatedAt: time.Now().Add(-2 * time.Minute)}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleMember}, nil)
+
+	_, err := svc.EditMessage(context.Background(), 1, 100, "hello")
+
+	assert.ErrorIs(t, err, ErrEditWindowExpired)
+	repo.AssertNotCalled(t, "UpdateMessageContent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetMessageRevisions_OutsiderIsForbidden(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(9)).Return(nil, nil)
+
+	revisions, err := svc.GetMessageRevisions(context.Background(), 9, 100)
+
+	assert.Nil(t, revisions)
+	assert.ErrorIs(t, err, ErrNotParticipant)
+}
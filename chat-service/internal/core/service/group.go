@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+// CreateGroup creates a group conversation owned by ownerID and adds the
+// initial members to it
+func (s *ChatService) CreateGroup(ctx context.Context, ownerID int64, title string, memberIDs []int64) (*domain.Conversation, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, ErrEmptyTitle
+	}
+	if err := s.requireUsers(ctx, memberIDs...); err != nil {
+		return nil, err
+	}
+
+	conv := &domain.Conversation{
+		IsGroup:   true,
+		Title:     &title,
+		CreatedBy: &ownerID,
+		CreatedAt: time.Now(),
+	}
+	// The group and its members are created together or not at all
+	err := s.repo.WithinTransaction(ctx, func(repo ports.ChatRepository) error {
+		if err := repo.CreateConversation(ctx, conv); err != nil {
+			return err
+		}
+
+		owner := &domain.Participant{ConversationID: conv.ID, UserID: ownerID, Role: domain.RoleOwner, JoinedAt: time.Now()}
+		if err := repo.AddParticipant(ctx, owner); err != nil {
+			return err
+		}
+
+		seen := map[int64]bool{ownerID: true}
+		for _, userID := range memberIDs {
+			if seen[userID] {
+				continue
+			}
+			seen[userID] = true
+
+			member := &domain.Participant{ConversationID: conv.ID, UserID: userID, Role: domain.RoleMember, JoinedAt: time.Now()}
+			if err := repo.AddParticipant(ctx, member); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conv, nil
+}
+
+// AddMembers adds users to a group. Only owners and admins can do this;
+// users that are already members are skipped.
+func (s *ChatService) AddMembers(ctx context.Context, actorID, conversationID int64, userIDs []int64) ([]domain.Participant, error) {
+	if _, err := s.groupParticipant(ctx, conversationID, actorID, ActionManageMembers); err != nil {
+		return nil, err
+	}
+	if err := s.requireUsers(ctx, userIDs...); err != nil {
+		return nil, err
+	}
+
+	// Everyone is added or nobody is
+	var added []domain.Participant
+	err := s.repo.WithinTransaction(ctx, func(repo ports.ChatRepository) error {
+		added = make([]domain.Participant, 0, len(userIDs))
+		for _, userID := range userIDs {
+			existing, err := repo.GetParticipant(ctx, conversationID, userID)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				continue
+			}
+
+			member := &domain.Participant{ConversationID: conversationID, UserID: userID, Role: domain.RoleMember, JoinedAt: time.Now()}
+			if err := repo.AddParticipant(ctx, member); err != nil {
+				return err
+			}
+			added = append(added, *member)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+// RemoveMember kicks userID out of a group. Owners can remove anyone but
+// themselves, admins can only remove plain members.
+func (s *ChatService) RemoveMember(ctx context.Context, actorID, conversationID, userID int64) error {
+	if actorID == userID {
+		return s.LeaveGroup(ctx, actorID, conversationID)
+	}
+
+	actor, err := s.groupParticipant(ctx, conversationID, actorID, ActionManageMembers)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.repo.GetParticipant(ctx, conversationID, userID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrNotParticipant
+	}
+	if roleRank(target.Role) >= roleRank(actor.Role) {
+		return forbidden(actorID, conversationID, ActionManageMembers, ErrPermissionDenied)
+	}
+
+	return s.repo.RemoveParticipant(ctx, conversationID, userID)
+}
+
+// LeaveGroup removes userID from a group. When the owner leaves, ownership
+// passes to the longest-standing admin, or to the oldest member if there
+// are no admins.
+func (s *ChatService) LeaveGroup(ctx context.Context, userID, conversationID int64) error {
+	member, err := s.groupParticipant(ctx, conversationID, userID, ActionLeave)
+	if err != nil {
+		return err
+	}
+
+	// Ownership passes on and the owner leaves together, a group is never
+	// left without an owner or with two
+	return s.repo.WithinTransaction(ctx, func(repo ports.ChatRepository) error {
+		if member.Role == domain.RoleOwner {
+			parts, err := repo.GetParticipants(ctx, conversationID)
+			if err != nil {
+				return err
+			}
+
+			var heir *domain.Participant
+			for i := range parts {
+				p := &parts[i]
+				if p.UserID == userID {
+					continue
+				}
+				if heir == nil || roleRank(p.Role) > roleRank(heir.Role) {
+					heir = p
+				}
+			}
+
+			if heir != nil {
+				if err := repo.UpdateParticipantRole(ctx, conversationID, heir.UserID, domain.RoleOwner); err != nil {
+					return err
+				}
+			}
+		}
+
+		return repo.RemoveParticipant(ctx, conversationID, userID)
+	})
+}
+
+// ChangeRole sets the role of userID in a group. Only the owner can change
+// roles; promoting someone to owner demotes the current owner to admin.
+func (s *ChatService) ChangeRole(ctx context.Context, actorID, conversationID, userID int64, role string) error {
+	if !domain.IsValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	if _, err := s.groupParticipant(ctx, conversationID, actorID, ActionChangeRoles); err != nil {
+		return err
+	}
+	if actorID == userID {
+		return forbidden(actorID, conversationID, ActionChangeRoles, ErrPermissionDenied)
+	}
+
+	target, err := s.repo.GetParticipant(ctx, conversationID, userID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrNotParticipant
+	}
+
+	// A handover promotes and demotes together
+	return s.repo.WithinTransaction(ctx, func(repo ports.ChatRepository) error {
+		if err := repo.UpdateParticipantRole(ctx, conversationID, userID, role); err != nil {
+			return err
+		}
+
+		if role == domain.RoleOwner {
+			return repo.UpdateParticipantRole(ctx, conversationID, actorID, domain.RoleAdmin)
+		}
+		return nil
+	})
+}
+
+// GetMembers lists the participants of a group the caller belongs to
+func (s *ChatService) GetMembers(ctx context.Context, userID, conversationID int64) ([]domain.Participant, error) {
+	if _, err := s.groupParticipant(ctx, conversationID, userID, ActionViewMembers); err != nil {
+		return nil, err
+	}
+	return s.repo.GetParticipants(ctx, conversationID)
+}
+
+// SendGroupMessage stores a message once and fans it out to every online
+// participant of the group. Retries are handled like in SendMessage.
+func (s *ChatService) SendGroupMessage(ctx context.Context, senderID, conversationID int64, content, clientMessageID string, attachmentIDs ...int64) (*domain.Message, error) {
+	if !validClientMessageID(clientMessageID) {
+		return nil, ErrInvalidClientMessageID
+	}
+	if _, err := s.groupParticipant(ctx, conversationID, senderID, ActionSendMessage); err != nil {
+		return nil, err
+	}
+
+	original, err := s.previousSend(ctx, conversationID, senderID, clientMessageID)
+	if err != nil {
+		return nil, err
+	}
+	if original != nil {
+		return original, nil
+	}
+
+	attachments, err := s.pendingAttachments(ctx, senderID, attachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &domain.Message{
+		ConversationID:  conversationID,
+		SenderID:        senderID,
+		Content:         content,
+		ClientMessageID: clientMessageIDPtr(clientMessageID),
+		CreatedAt:       time.Now(),
+	}
+	stored, duplicate, err := s.saveMessage(ctx, msg, attachments)
+	if err != nil || duplicate {
+		return stored, err
+	}
+
+	parts, err := s.repo.GetParticipants(ctx, conversationID)
+	if err != nil {
+		// The message is stored, recipients will see it in their history
+		return msg, nil
+	}
+
+	recipients := make([]int64, 0, len(parts))
+	for _, p := range parts {
+		if p.UserID != senderID {
+			recipients = append(recipients, p.UserID)
+		}
+	}
+	s.deliver(ctx, recipients, websocket.EventMessage, msg)
+
+	return msg, nil
+}
+
+// groupParticipant authorizes the caller for action and makes sure the
+// conversation is a group. Membership is checked first, so outsiders learn
+// nothing about the conversation.
+func (s *ChatService) groupParticipant(ctx context.Context, conversationID, userID int64, action Action) (*domain.Participant, error) {
+	part, err := s.authorize(ctx, userID, conversationID, action)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := s.repo.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		return nil, ErrConversationNotFound
+	}
+	if !conv.IsGroup {
+		return nil, ErrNotGroupConversation
+	}
+	return part, nil
+}
+
+func roleRank(role string) int {
+	switch role {
+	case domain.RoleOwner:
+		return 3
+	case domain.RoleAdmin:
+		return 2
+	case domain.RoleMember:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+func TestCreateGroup_AddsOwnerAndMembers(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("CreateConversation", mock.Anything, mock.AnythingOfType("*domain.Conversation")).
+		Return(nil)
+
+	repo.On("AddParticipant", mock.Anything, mock.MatchedBy(func(p *domain.Participant) bool {
+		return p.UserID == 1 && p.Role == domain.RoleOwner
+	})).Return(nil).Once()
+
+	repo.On("AddParticipant", mock.Anything, mock.MatchedBy(func(p *domain.Participant) bool {
+		return (p.UserID == 2 || p.UserID == 3) && p.Role == domain.RoleMember
+	})).Return(nil).Twice()
+
+	// Owner and duplicates in the member list are skipped
+	conv, err := svc.CreateGroup(ctx, 1, "  Team  ", []int64{2, 3, 1, 2})
+
+	assert.NoError(t, err)
+	assert.True(t, conv.IsGroup)
+	assert.Equal(t, "Team", *conv.Title)
+
+	repo.AssertExpectations(t)
+}
+
+func TestCreateGroup_EmptyTitle(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	conv, err := svc.CreateGroup(context.Background(), 1, " ", nil)
+
+	assert.Nil(t, conv)
+	assert.ErrorIs(t, err, ErrEmptyTitle)
+}
+
+func TestRemoveMember_AdminCannotRemoveAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetConversationByID", mock.Anything, int64(10)).
+		Return(&domain.Conversation{ID: 10, IsGroup: true}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleAdmin}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleAdmin}, nil)
+
+	err := svc.RemoveMember(ctx, 1, 10, 2)
+
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	repo.AssertNotCalled(t, "RemoveParticipant", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLeaveGroup_OwnerTransfersOwnership(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetConversationByID", mock.Anything, int64(10)).
+		Return(&domain.Conversation{ID: 10, IsGroup: true}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleOwner}, nil)
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{
+			{ConversationID: 10, UserID: 1, Role: domain.RoleOwner},
+			{ConversationID: 10, UserID: 2, Role: domain.RoleMember},
+			{ConversationID: 10, UserID: 3, Role: domain.RoleAdmin},
+		}, nil)
+	// Handing over and leaving happen in one transaction
+	inTransaction := func(mock.Arguments) { assert.True(t, repo.inTransaction) }
+	repo.On("UpdateParticipantRole", mock.Anything, int64(10), int64(3), domain.RoleOwner).
+		Run(inTransaction).Return(nil)
+	repo.On("RemoveParticipant", mock.Anything, int64(10), int64(1)).
+		Run(inTransaction).Return(nil)
+
+	err := svc.LeaveGroup(ctx, 1, 10)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestChangeRole_HandoverFailsAsAWhole(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetConversationByID", mock.Anything, int64(10)).
+		Return(&domain.Conversation{ID: 10, IsGroup: true}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleOwner}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleAdmin}, nil)
+	inTransaction := func(mock.Arguments) { assert.True(t, repo.inTransaction) }
+	repo.On("UpdateParticipantRole", mock.Anything, int64(10), int64(2), domain.RoleOwner).
+		Run(inTransaction).Return(nil)
+	repo.On("UpdateParticipantRole", mock.Anything, int64(10), int64(1), domain.RoleAdmin).
+		Run(inTransaction).Return(errors.New("connection reset"))
+
+	err := svc.ChangeRole(ctx, 1, 10, 2, domain.RoleOwner)
+
+	assert.Error(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestSendGroupMessage_NotParticipant(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetConversationByID", mock.Anything, int64(10)).
+		Return(&domain.Conversation{ID: 10, IsGroup: true}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(5)).
+		Return(nil, nil)
+
+	msg, err := svc.SendGroupMessage(ctx, 5, 10, "hi", "")
+
+	assert.Nil(t, msg)
+	assert.ErrorIs(t, err, ErrNotParticipant)
+	repo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// GetHistory returns one page of a conversation's messages to one of its
+// participants. An empty cursor starts at the newest message (backward) or
+// the oldest one (forward); otherwise the page starts right past the cursor.
+// Thread replies are left out, they are paged with GetThread.
+func (s *ChatService) GetHistory(ctx context.Context, userID, conversationID int64, cursor string, direction domain.Direction, limit int) (*domain.MessagePage, error) {
+	if direction == "" {
+		direction = domain.DirectionBackward
+	}
+	query, err := historyQuery(userID, cursor, direction, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.authorize(ctx, userID, conversationID, ActionReadHistory); err != nil {
+		return nil, err
+	}
+
+	return s.messagePage(ctx, conversationID, query)
+}
+
+// historyQuery validates the paging parameters of a history request
+func historyQuery(userID int64, cursor string, direction domain.Direction, limit int) (domain.HistoryQuery, error) {
+	if direction != domain.DirectionBackward && direction != domain.DirectionForward {
+		return domain.HistoryQuery{}, ErrInvalidDirection
+	}
+
+	position, err := domain.DecodePageCursor(cursor)
+	if err != nil {
+		return domain.HistoryQuery{}, err
+	}
+
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	return domain.HistoryQuery{
+		ViewerID:  userID,
+		Cursor:    position,
+		Direction: direction,
+		Limit:     limit,
+	}, nil
+}
+
+// messagePage loads one page for query and computes its cursors
+func (s *ChatService) messagePage(ctx context.Context, conversationID int64, query domain.HistoryQuery) (*domain.MessagePage, error) {
+	limit := query.Limit
+
+	// Ask for one extra message to know if there is a next page
+	query.Limit = limit + 1
+	messages, err := s.repo.GetMessages(ctx, conversationID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.MessagePage{Messages: messages, Direction: query.Direction}
+	if page.Messages == nil {
+		page.Messages = []domain.Message{}
+	}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		page.NextCursor = messageCursor(page.Messages[limit-1]).Encode()
+	}
+	// Coming from a cursor means there is something on the other side
+	if query.Cursor != nil && len(page.Messages) > 0 {
+		page.PrevCursor = messageCursor(page.Messages[0]).Encode()
+	}
+
+	return page, nil
+}
+
+func messageCursor(msg domain.Message) domain.PageCursor {
+	return domain.PageCursor{Time: msg.CreatedAt, ID: msg.ID}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+func TestGetHistory_FirstPageBackward(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	now := time.Now()
+	messages := []domain.Message{
+		{ID: 3, CreatedAt: now},
+		{ID: 2, CreatedAt: now.Add(-time.Second)},
+		{ID: 1, CreatedAt: now.Add(-2 * time.Second)},
+	}
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleMember}, nil)
+	// limit 2 -> repo is asked for 3
+	repo.On("GetMessages", mock.Anything, int64(10), domain.HistoryQuery{ViewerID: 1, Direction: domain.DirectionBackward, Limit: 3}).
+		Return(messages, nil)
+
+	page, err := svc.GetHistory(context.Background(), 1, 10, "", "", 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Messages, 2)
+	assert.Empty(t, page.PrevCursor)
+
+	next, err := domain.DecodePageCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), next.ID)
+
+	repo.AssertExpectations(t)
+}
+
+func TestGetHistory_ForwardFromCursor(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	now := time.Now()
+	start := domain.PageCursor{Time: now, ID: 5}
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleMember}, nil)
+
+	repo.On("GetMessages", mock.Anything, int64(10), mock.MatchedBy(func(q domain.HistoryQuery) bool {
+		return q.Direction == domain.DirectionForward && q.Cursor.ID == 5 && q.Limit == maxHistoryLimit+1
+	})).Return([]domain.Message{{ID: 6, CreatedAt: now.Add(time.Second)}}, nil)
+
+	page, err := svc.GetHistory(context.Background(), 1, 10, start.Encode(), domain.DirectionForward, 10000)
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Messages, 1)
+	assert.Empty(t, page.NextCursor)
+
+	prev, err := domain.DecodePageCursor(page.PrevCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), prev.ID)
+
+	repo.AssertExpectations(t)
+}
+
+func TestGetHistory_InvalidDirection(t *testing.T) {
+	svc := NewChatService(new(MockRepo), websocket.NewClientManager())
+
+	_, err := svc.GetHistory(context.Background(), 1, 10, "", "sideways", 0)
+
+	assert.ErrorIs(t, err, ErrInvalidDirection)
+}
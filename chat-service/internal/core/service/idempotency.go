@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+// maxClientMessageIDLength fits a UUID or ULID with room to spare
+const maxClientMessageIDLength = 64
+
+// validClientMessageID reports whether id can tag a send; empty means the
+// client does not ask for idempotency
+func validClientMessageID(id string) bool {
+	return len(id) <= maxClientMessageIDLength
+}
+
+// previousSend returns the message an earlier attempt of the same send
+// stored, nil if there is none or the client sent no id
+func (s *ChatService) previousSend(ctx context.Context, conversationID, senderID int64, clientMessageID string) (*domain.Message, error) {
+	if clientMessageID == "" {
+		return nil, nil
+	}
+	return s.repo.GetMessageByClientID(ctx, conversationID, senderID, clientMessageID)
+}
+
+// saveMessage stores msg and links its attachments in one transaction.
+// When a concurrent attempt of the same send won the race, its message is
+// returned instead and duplicate is true; the caller must then not deliver
+// anything.
+func (s *ChatService) saveMessage(ctx context.Context, msg *domain.Message, attachments []domain.Attachment) (stored *domain.Message, duplicate bool, err error) {
+	err = s.repo.WithinTransaction(ctx, func(repo ports.ChatRepository) error {
+		if err := repo.SaveMessage(ctx, msg); err != nil {
+			return err
+		}
+		return linkAttachments(ctx, repo, msg, attachments)
+	})
+	// The failed transaction is rolled back, look the winner up outside it
+	if errors.Is(err, ports.ErrDuplicateMessage) {
+		original, err := s.previousSend(ctx, msg.ConversationID, msg.SenderID, *msg.ClientMessageID)
+		if err != nil {
+			return nil, false, err
+		}
+		if original == nil {
+			return nil, false, ports.ErrDuplicateMessage
+		}
+		return original, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return msg, false, nil
+}
+
+func clientMessageIDPtr(id string) *string {
+	if id == "" {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+func TestSendMessage_RetryReturnsOriginal(t *testing.T) {
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+
+	clientID := "c-1"
+	original := &domain.Message{ID: 100, ConversationID: 10, SenderID: 1, Content: "hi", ClientMessageID: &clientID}
+	repo.On("FindOneToOneConversation", mock.Anything, int64(1), int64(2)).
+		Return(&domain.Conversation{ID: 10}, nil)
+	repo.On("GetMessageByClientID", mock.Anything, int64(10), int64(1), "c-1").Return(original, nil)
+
+	msg, err := svc.SendMessage(context.Background(), 1, 2, "hi", "c-1")
+
+	assert.NoError(t, err)
+	assert.Same(t, original, msg)
+	assert.Empty(t, bus.Events())
+	repo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
+
+func TestSendGroupMessage_ConcurrentRetryReturnsWinner(t *testing.T) {
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+
+	clientID := "c-1"
+	winner := &domain.Message{ID: 100, ConversationID: 10, SenderID: 2, Content: "hi", ClientMessageID: &clientID}
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("GetConversationByID", mock.Anything, int64(10)).
+		Return(&domain.Conversation{ID: 10, IsGroup: true}, nil)
+	// Not stored yet when checked, stored by the other attempt when saving
+	repo.On("GetMessageByClientID", mock.Anything, int64(10), int64(2), "c-1").Return(nil, nil).Once()
+	repo.On("SaveMessage", mock.Anything, mock.Anything).Return(ports.ErrDuplicateMessage)
+	repo.On("GetMessageByClientID", mock.Anything, int64(10), int64(2), "c-1").Return(winner, nil).Once()
+
+	msg, err := svc.SendGroupMessage(context.Background(), 2, 10, "hi", "c-1")
+
+	assert.NoError(t, err)
+	assert.Same(t, winner, msg)
+	assert.Empty(t, bus.Events())
+	repo.AssertExpectations(t)
+}
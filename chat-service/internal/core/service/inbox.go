@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+const (
+	defaultInboxLimit = 20
+	maxInboxLimit     = 100
+)
+
+// ListConversations returns a page of userID's conversations, pinned ones
+// first and then most recently active first, each with its last message,
+// the other participants (with their names and avatars) and the number of
+// unread messages
+func (s *ChatService) ListConversations(ctx context.Context, userID int64, cursor string, limit int) (*domain.InboxPage, error) {
+	after, err := domain.DecodePageCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultInboxLimit
+	}
+	if limit > maxInboxLimit {
+		limit = maxInboxLimit
+	}
+
+	// Ask for one extra conversation to know if there is a next page
+	entries, err := s.repo.GetInbox(ctx, userID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.InboxPage{Conversations: entries}
+	if len(entries) > limit {
+		page.Conversations = entries[:limit]
+		last := page.Conversations[limit-1]
+		page.NextCursor = domain.PageCursor{Time: last.LastActivityAt, ID: last.ID, Pinned: last.Pinned}.Encode()
+	}
+	if len(page.Conversations) == 0 {
+		return page, nil
+	}
+
+	ids := make([]int64, len(page.Conversations))
+	for i, entry := range page.Conversations {
+		ids[i] = entry.ID
+	}
+	parts, err := s.repo.GetParticipantsByConversations(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[int64][]domain.InboxMember, len(ids))
+	for _, p := range parts {
+		if p.UserID == userID {
+			continue
+		}
+		members[p.ConversationID] = append(members[p.ConversationID], domain.InboxMember{UserID: p.UserID, Role: p.Role})
+	}
+	for i := range page.Conversations {
+		page.Conversations[i].Participants = members[page.Conversations[i].ID]
+	}
+	s.fillProfiles(ctx, page.Conversations)
+
+	return page, nil
+}
+
+// UpdateConversationSettings pins or mutes a conversation for userID only.
+// A nil mutedUntil unmutes it.
+func (s *ChatService) UpdateConversationSettings(ctx context.Context, userID, conversationID int64, pinned bool, mutedUntil *time.Time) error {
+	if _, err := s.authorize(ctx, userID, conversationID, ActionUpdateSettings); err != nil {
+		return err
+	}
+	return s.repo.UpdateParticipantSettings(ctx, conversationID, userID, pinned, mutedUntil)
+}
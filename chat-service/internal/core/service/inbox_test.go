@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+func TestListConversations_PagesAndFillsParticipants(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	now := time.Now()
+	entries := []domain.InboxEntry{
+		{Conversation: domain.Conversation{ID: 30}, UnreadCount: 2, LastActivityAt: now},
+		{Conversation: domain.Conversation{ID: 20}, LastActivityAt: now.Add(-time.Minute)},
+		{Conversation: domain.Conversation{ID: 10}, LastActivityAt: now.Add(-time.Hour)},
+	}
+
+	// limit 2 -> repo is asked for 3
+	repo.On("GetInbox", mock.Anything, int64(1), (*domain.PageCursor)(nil), 3).Return(entries, nil)
+	repo.On("GetParticipantsByConversations", mock.Anything, []int64{30, 20}).
+		Return([]domain.Participant{
+			{ConversationID: 30, UserID: 1, Role: domain.RoleMember},
+			{ConversationID: 30, UserID: 2, Role: domain.RoleMember},
+			{ConversationID: 20, UserID: 1, Role: domain.RoleOwner},
+			{ConversationID: 20, UserID: 3, Role: domain.RoleMember},
+			{ConversationID: 20, UserID: 4, Role: domain.RoleAdmin},
+		}, nil)
+
+	page, err := svc.ListConversations(ctx, 1, "", 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Conversations, 2)
+	assert.Equal(t, []domain.InboxMember{{UserID: 2, Role: domain.RoleMember}}, page.Conversations[0].Participants)
+	assert.Len(t, page.Conversations[1].Participants, 2)
+
+	next, err := domain.DecodePageCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), next.ID)
+	assert.True(t, next.Time.Equal(entries[1].LastActivityAt))
+
+	repo.AssertExpectations(t)
+}
+
+func TestListConversations_CursorContinuesAfterPinned(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	now := time.Now()
+	entries := []domain.InboxEntry{
+		{Conversation: domain.Conversation{ID: 10}, Pinned: true, LastActivityAt: now.Add(-time.Hour)},
+		{Conversation: domain.Conversation{ID: 30}, LastActivityAt: now},
+	}
+	repo.On("GetInbox", mock.Anything, int64(1), (*domain.PageCursor)(nil), 2).Return(entries, nil)
+	repo.On("GetParticipantsByConversations", mock.Anything, []int64{10}).Return([]domain.Participant{}, nil)
+
+	page, err := svc.ListConversations(context.Background(), 1, "", 1)
+
+	assert.NoError(t, err)
+	next, err := domain.DecodePageCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), next.ID)
+	assert.True(t, next.Pinned, "the next page must start after the pinned conversations")
+	repo.AssertExpectations(t)
+}
+
+func TestListConversations_LastPageHasNoCursor(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetInbox", mock.Anything, int64(1), (*domain.PageCursor)(nil), defaultInboxLimit+1).
+		Return([]domain.InboxEntry{}, nil)
+
+	page, err := svc.ListConversations(context.Background(), 1, "", 0)
+
+	assert.NoError(t, err)
+	assert.Empty(t, page.Conversations)
+	assert.Empty(t, page.NextCursor)
+	repo.AssertExpectations(t)
+}
+
+func TestUpdateConversationSettings_NotParticipant(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).Return(nil, nil)
+
+	err := svc.UpdateConversationSettings(context.Background(), 1, 10, true, nil)
+
+	assert.ErrorIs(t, err, ErrNotParticipant)
+	repo.AssertNotCalled(t, "UpdateParticipantSettings", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+const (
+	// defaultPresenceTTL is how long a connection counts as alive after its
+	// last heartbeat
+	defaultPresenceTTL = 60 * time.Second
+	// defaultAwayAfter is how long a connected user can be idle before
+	// showing as away
+	defaultAwayAfter = 5 * time.Minute
+	// maxPresenceBatch caps the users asked about in one GetPresence call
+	maxPresenceBatch = 200
+)
+
+// SetPresence enables presence tracking. Status changes are delivered to
+// the user's peers and passed on to sink, which may be nil.
+func (s *ChatService) SetPresence(store ports.PresenceStore, sink ports.PresenceSink) {
+	s.presence = store
+	s.presenceSink = sink
+}
+
+// SetPresenceTimeouts changes how long connections live without a
+// heartbeat and how long users can idle before going away
+func (s *ChatService) SetPresenceTimeouts(ttl, awayAfter time.Duration) {
+	if ttl > 0 {
+		s.presenceTTL = ttl
+	}
+	if awayAfter > 0 {
+		s.awayAfter = awayAfter
+	}
+}
+
+// PresenceTTL is how long a connection stays online without a heartbeat.
+// Connections must call Heartbeat well within it.
+func (s *ChatService) PresenceTTL() time.Duration {
+	return s.presenceTTL
+}
+
+// Connected marks a new connection of userID alive and active
+func (s *ChatService) Connected(ctx context.Context, userID int64, connID string) {
+	s.Heartbeat(ctx, userID, connID, true)
+}
+
+// Heartbeat keeps a connection alive. active means the user did something
+// (sent an event), which brings them back from away.
+func (s *ChatService) Heartbeat(ctx context.Context, userID int64, connID string, active bool) {
+	if s.presence == nil {
+		return
+	}
+	if err := s.presence.Heartbeat(ctx, userID, connID, s.presenceTTL, active); err != nil {
+		log.Printf("Presence heartbeat for user %d failed: %v", userID, err)
+		return
+	}
+	// Going idle is noticed by the sweeper, only activity needs a refresh
+	if active {
+		s.refreshPresence(ctx, userID)
+	}
+}
+
+// Disconnected drops a closed connection; the user goes offline once their
+// last connection is gone
+func (s *ChatService) Disconnected(ctx context.Context, userID int64, connID string) {
+	if s.presence == nil {
+		return
+	}
+	if err := s.presence.Disconnect(ctx, userID, connID); err != nil {
+		log.Printf("Presence disconnect for user %d failed: %v", userID, err)
+	}
+	s.refreshPresence(ctx, userID)
+}
+
+// SweepPresence re-evaluates every user announced as online or away, so
+// idle users go away and users whose connections expired (their instance
+// died, say) go offline
+func (s *ChatService) SweepPresence(ctx context.Context) {
+	if s.presence == nil {
+		return
+	}
+	userIDs, err := s.presence.Announced(ctx)
+	if err != nil {
+		log.Printf("Presence sweep failed: %v", err)
+		return
+	}
+	for _, userID := range userIDs {
+		s.refreshPresence(ctx, userID)
+	}
+}
+
+// RunPresenceSweeper calls SweepPresence every interval until ctx is
+// cancelled. Every instance may run one, each change is announced once.
+func (s *ChatService) RunPresenceSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SweepPresence(ctx)
+		}
+	}
+}
+
+// GetPresence returns the presence of the listed users that share a
+// conversation with viewerID (and of viewerID themselves). Other users are
+// left out so presence is never disclosed to strangers.
+func (s *ChatService) GetPresence(ctx context.Context, viewerID int64, userIDs []int64) ([]domain.Presence, error) {
+	if s.presence == nil {
+		return nil, ErrPresenceUnavailable
+	}
+	if len(userIDs) > maxPresenceBatch {
+		return nil, ErrTooManyUsers
+	}
+
+	peers, err := s.repo.GetPeers(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	visible := map[int64]bool{viewerID: true}
+	for _, peerID := range peers {
+		visible[peerID] = true
+	}
+
+	allowed := make([]int64, 0, len(userIDs))
+	seen := make(map[int64]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if visible[userID] && !seen[userID] {
+			seen[userID] = true
+			allowed = append(allowed, userID)
+		}
+	}
+
+	states, err := s.presence.States(ctx, allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]domain.Presence, 0, len(allowed))
+	for _, userID := range allowed {
+		result = append(result, s.presenceOf(userID, states[userID], now))
+	}
+	return result, nil
+}
+
+// refreshPresence recomputes a user's status and, when it changed,
+// announces it to their peers and the sink
+func (s *ChatService) refreshPresence(ctx context.Context, userID int64) {
+	now := time.Now()
+	state, previous, err := s.presence.UpdateStatus(ctx, userID, func(state ports.PresenceState) domain.PresenceStatus {
+		return s.presenceOf(userID, state, now).Status
+	})
+	if err != nil {
+		log.Printf("Presence update for user %d failed: %v", userID, err)
+		return
+	}
+	presence := s.presenceOf(userID, state, now)
+	if previous == presence.Status {
+		return
+	}
+
+	peers, err := s.repo.GetPeers(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to load peers of user %d: %v", userID, err)
+	} else {
+		s.deliver(ctx, peers, websocket.EventPresence, presence)
+	}
+
+	if s.presenceSink != nil {
+		if err := s.presenceSink.PresenceChanged(ctx, presence); err != nil {
+			log.Printf("Failed to record presence of user %d: %v", userID, err)
+		}
+	}
+}
+
+func (s *ChatService) presenceOf(userID int64, state ports.PresenceState, now time.Time) domain.Presence {
+	presence := domain.Presence{UserID: userID, Status: domain.PresenceOffline}
+	if !state.LastActiveAt.IsZero() {
+		lastSeen := state.LastActiveAt
+		presence.LastSeenAt = &lastSeen
+	}
+
+	if state.Connected {
+		presence.Status = domain.PresenceOnline
+		if now.Sub(state.LastActiveAt) >= s.awayAfter {
+			presence.Status = domain.PresenceAway
+		}
+	}
+	return presence
+}
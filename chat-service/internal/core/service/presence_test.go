@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+// memoryPresence is an in-memory PresenceStore and PresenceSink
+type memoryPresence struct {
+	mu       sync.Mutex
+	conns    map[int64]map[string]time.Time
+	active   map[int64]time.Time
+	statuses map[int64]domain.PresenceStatus
+	changes  []domain.Presence
+}
+
+func newMemoryPresence() *memoryPresence {
+	return &memoryPresence{
+		conns:    make(map[int64]map[string]time.Time),
+		active:   make(map[int64]time.Time),
+		statuses: make(map[int64]domain.PresenceStatus),
+	}
+}
+
+func (p *memoryPresence) Heartbeat(ctx context.Context, userID int64, connID string, ttl time.Duration, active bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conns[userID] == nil {
+		p.conns[userID] = make(map[string]time.Time)
+	}
+	p.conns[userID][connID] = time.Now().Add(ttl)
+	if active {
+		p.active[userID] = time.Now()
+	}
+	return nil
+}
+
+func (p *memoryPresence) Disconnect(ctx context.Context, userID int64, connID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.conns[userID], connID)
+	return nil
+}
+
+func (p *memoryPresence) States(ctx context.Context, userIDs []int64) (map[int64]ports.PresenceState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	states := make(map[int64]ports.PresenceState)
+	for _, userID := range userIDs {
+		states[userID] = p.state(userID)
+	}
+	return states, nil
+}
+
+func (p *memoryPresence) state(userID int64) ports.PresenceState {
+	state := ports.PresenceState{LastActiveAt: p.active[userID]}
+	for _, expiry := range p.conns[userID] {
+		if expiry.After(time.Now()) {
+			state.Connected = true
+		}
+	}
+	return state
+}
+
+func (p *memoryPresence) UpdateStatus(ctx context.Context, userID int64, derive func(ports.PresenceState) domain.PresenceStatus) (ports.PresenceState, domain.PresenceStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	previous, ok := p.statuses[userID]
+	if !ok {
+		previous = domain.PresenceOffline
+	}
+	state := p.state(userID)
+	p.statuses[userID] = derive(state)
+	return state, previous, nil
+}
+
+func (p *memoryPresence) Announced(ctx context.Context) ([]int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var userIDs []int64
+	for userID, status := range p.statuses {
+		if status != domain.PresenceOffline {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (p *memoryPresence) PresenceChanged(ctx context.Context, presence domain.Presence) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.changes = append(p.changes, presence)
+	return nil
+}
+
+func TestPresence_ConnectAndDisconnectAnnounceOnce(t *testing.T) {
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	store := newMemoryPresence()
+	svc := NewChatService(repo, bus)
+	svc.SetPresence(store, store)
+
+	repo.On("GetPeers", mock.Anything, int64(1)).Return([]int64{2, 3}, nil)
+
+	ctx := context.Background()
+	svc.Connected(ctx, 1, "phone")
+	// A second device and more activity change nothing for peers
+	svc.Connected(ctx, 1, "laptop")
+	svc.Heartbeat(ctx, 1, "phone", true)
+	svc.Disconnected(ctx, 1, "phone")
+	// The last connection going away does
+	svc.Disconnected(ctx, 1, "laptop")
+
+	assert.Equal(t, []string{websocket.EventPresence, websocket.EventPresence}, bus.Events())
+	if assert.Len(t, store.changes, 2) {
+		assert.Equal(t, domain.PresenceOnline, store.changes[0].Status)
+		assert.Equal(t, domain.PresenceOffline, store.changes[1].Status)
+		assert.NotNil(t, store.changes[1].LastSeenAt)
+	}
+}
+
+func TestPresence_SweepMarksIdleUsersAway(t *testing.T) {
+	repo := new(MockRepo)
+	store := newMemoryPresence()
+	svc := NewChatService(repo, &recordingBus{})
+	svc.SetPresence(store, store)
+	svc.SetPresenceTimeouts(time.Minute, 10*time.Millisecond)
+
+	repo.On("GetPeers", mock.Anything, int64(1)).Return([]int64{2}, nil)
+
+	ctx := context.Background()
+	svc.Connected(ctx, 1, "phone")
+	time.Sleep(20 * time.Millisecond)
+	svc.SweepPresence(ctx)
+
+	if assert.Len(t, store.changes, 2) {
+		assert.Equal(t, domain.PresenceAway, store.changes[1].Status)
+	}
+}
+
+func TestGetPresence_HidesStrangers(t *testing.T) {
+	repo := new(MockRepo)
+	store := newMemoryPresence()
+	svc := NewChatService(repo, &recordingBus{})
+	svc.SetPresence(store, store)
+
+	repo.On("GetPeers", mock.Anything, int64(1)).Return([]int64{2}, nil)
+	store.Heartbeat(context.Background(), 2, "phone", time.Minute, true)
+
+	presence, err := svc.GetPresence(context.Background(), 1, []int64{2, 9})
+
+	assert.NoError(t, err)
+	if assert.Len(t, presence, 1) {
+		assert.Equal(t, int64(2), presence[0].UserID)
+		assert.Equal(t, domain.PresenceOnline, presence[0].Status)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+// defaultReactions is the reaction set used unless configured otherwise
+var defaultReactions = []string{"👍", "❤️", "😂", "😮", "😢", "🙏"}
+
+// SetAllowedReactions replaces the set of emoji users may react with
+func (s *ChatService) SetAllowedReactions(emoji []string) {
+	if len(emoji) == 0 {
+		return
+	}
+	allowed := make(map[string]bool, len(emoji))
+	for _, e := range emoji {
+		allowed[e] = true
+	}
+	s.reactions = allowed
+}
+
+// AddReaction puts userID's emoji on a message. Adding the same emoji twice
+// is a no-op; participants hear about new reactions only.
+func (s *ChatService) AddReaction(ctx context.Context, userID, messageID int64, emoji string) (*domain.Reaction, error) {
+	reaction, err := s.reactionTarget(ctx, userID, messageID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := s.repo.AddReaction(ctx, reaction)
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		s.broadcastReaction(ctx, websocket.EventReactionAdded, reaction)
+	}
+	return reaction, nil
+}
+
+// RemoveReaction takes userID's emoji off a message
+func (s *ChatService) RemoveReaction(ctx context.Context, userID, messageID int64, emoji string) error {
+	reaction, err := s.reactionTarget(ctx, userID, messageID, emoji)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.repo.RemoveReaction(ctx, messageID, userID, emoji)
+	if err != nil {
+		return err
+	}
+	if removed {
+		s.broadcastReaction(ctx, websocket.EventReactionRemoved, reaction)
+	}
+	return nil
+}
+
+// reactionTarget validates a reaction request and builds the reaction
+func (s *ChatService) reactionTarget(ctx context.Context, userID, messageID int64, emoji string) (*domain.Reaction, error) {
+	if !s.reactions[emoji] {
+		return nil, ErrReactionNotAllowed
+	}
+
+	msg, err := s.repo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil || msg.DeletedAt != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if _, err := s.authorize(ctx, userID, msg.ConversationID, ActionReact); err != nil {
+		return nil, err
+	}
+
+	return &domain.Reaction{
+		ConversationID: msg.ConversationID,
+		MessageID:      messageID,
+		UserID:         userID,
+		Emoji:          emoji,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+func (s *ChatService) broadcastReaction(ctx context.Context, eventType string, reaction *domain.Reaction) {
+	parts, err := s.repo.GetParticipants(ctx, reaction.ConversationID)
+	if err != nil {
+		return
+	}
+
+	recipients := make([]int64, 0, len(parts))
+	for _, p := range parts {
+		recipients = append(recipients, p.UserID)
+	}
+	s.deliver(ctx, recipients, eventType, reaction)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+func TestAddReaction_BroadcastsOnlyWhenNew(t *testing.T) {
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("AddReaction", mock.Anything, mock.MatchedBy(func(r *domain.Reaction) bool {
+		return r.MessageID == 100 && r.UserID == 2 && r.Emoji == "👍"
+	})).Return(true, nil).Once()
+	repo.On("AddReaction", mock.Anything, mock.Anything).Return(false, nil).Once()
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{{UserID: 1}, {UserID: 2}}, nil)
+
+	_, err := svc.AddReaction(context.Background(), 2, 100, "👍")
+	assert.NoError(t, err)
+	// The same emoji again changes nothing
+	_, err = svc.AddReaction(context.Background(), 2, 100, "👍")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{websocket.EventReactionAdded}, bus.Events())
+	repo.AssertExpectations(t)
+}
+
+func TestAddReaction_RespectsAllowedSet(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+	svc.SetAllowedReactions([]string{"+1"})
+
+	_, err := svc.AddReaction(context.Background(), 2, 100, "👍")
+
+	assert.ErrorIs(t, err, ErrReactionNotAllowed)
+	repo.AssertNotCalled(t, "GetMessageByID", mock.Anything, mock.Anything)
+}
+
+func TestRemoveReaction_OutsiderIsForbidden(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(9)).Return(nil, nil)
+
+	err := svc.RemoveReaction(context.Background(), 9, 100, "👍")
+
+	assert.ErrorIs(t, err, ErrNotParticipant)
+	repo.AssertNotCalled(t, "RemoveReaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
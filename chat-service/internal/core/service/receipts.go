@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+// MarkDelivered records that every message up to seq reached userID
+func (s *ChatService) MarkDelivered(ctx context.Context, userID, conversationID, seq int64) (*domain.Receipt, error) {
+	return s.updateWatermarks(ctx, userID, conversationID, seq, 0)
+}
+
+// MarkRead records that userID has read every message up to seq. Reading
+// implies delivery, so the delivered watermark moves too.
+func (s *ChatService) MarkRead(ctx context.Context, userID, conversationID, seq int64) (*domain.Receipt, error) {
+	return s.updateWatermarks(ctx, userID, conversationID, seq, seq)
+}
+
+// UpdateReceipt dispatches to MarkDelivered or MarkRead by receipt kind
+func (s *ChatService) UpdateReceipt(ctx context.Context, userID, conversationID, seq int64, kind string) (*domain.Receipt, error) {
+	switch kind {
+	case domain.ReceiptDelivered:
+		return s.MarkDelivered(ctx, userID, conversationID, seq)
+	case domain.ReceiptRead:
+		return s.MarkRead(ctx, userID, conversationID, seq)
+	}
+	return nil, ErrInvalidReceipt
+}
+
+// GetMessageReaders lists the participants, other than the sender, that
+// have read the given message
+func (s *ChatService) GetMessageReaders(ctx context.Context, userID, messageID int64) ([]domain.Participant, error) {
+	msg, err := s.repo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, ErrMessageNotFound
+	}
+
+	parts, err := s.repo.GetParticipants(ctx, msg.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccess(findParticipant(parts, userID), userID, msg.ConversationID, ActionReadHistory); err != nil {
+		return nil, err
+	}
+
+	readers := make([]domain.Participant, 0, len(parts))
+	for _, p := range parts {
+		if p.UserID != msg.SenderID && p.ReadSeq >= msg.Seq {
+			readers = append(readers, p)
+		}
+	}
+	return readers, nil
+}
+
+func (s *ChatService) updateWatermarks(ctx context.Context, userID, conversationID, deliveredSeq, readSeq int64) (*domain.Receipt, error) {
+	part, err := s.authorize(ctx, userID, conversationID, ActionUpdateReceipt)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err := s.repo.UpdateWatermarks(ctx, conversationID, userID, deliveredSeq, readSeq)
+	if err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		// Watermarks were already at or past seq, nobody needs to hear about it
+		return &domain.Receipt{
+			ConversationID: conversationID,
+			UserID:         userID,
+			DeliveredSeq:   part.DeliveredSeq,
+			ReadSeq:        part.ReadSeq,
+		}, nil
+	}
+
+	parts, err := s.repo.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return receipt, nil
+	}
+
+	// Push the receipt to the other participants, i.e. the senders of the
+	// acknowledged messages
+	recipients := make([]int64, 0, len(parts))
+	for _, p := range parts {
+		if p.UserID != userID {
+			recipients = append(recipients, p.UserID)
+		}
+	}
+	s.deliver(ctx, recipients, websocket.EventReceipt, receipt)
+
+	return receipt, nil
+}
+
+func findParticipant(parts []domain.Participant, userID int64) *domain.Participant {
+	for i := range parts {
+		if parts[i].UserID == userID {
+			return &parts[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+func TestMarkRead_MovesBothWatermarks(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("UpdateWatermarks", mock.Anything, int64(10), int64(2), int64(7), int64(7)).
+		Return(&domain.Receipt{ConversationID: 10, UserID: 2, DeliveredSeq: 7, ReadSeq: 7}, nil)
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{{UserID: 1}, {UserID: 2}}, nil)
+
+	receipt, err := svc.MarkRead(ctx, 2, 10, 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), receipt.ReadSeq)
+	repo.AssertExpectations(t)
+}
+
+func TestGetMessageReaders_ExcludesSenderAndUnread(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1, Seq: 5}, nil)
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{
+			{UserID: 1, Role: domain.RoleMember, ReadSeq: 5},
+			{UserID: 2, Role: domain.RoleMember, ReadSeq: 5},
+			{UserID: 3, Role: domain.RoleMember, ReadSeq: 4},
+		}, nil)
+
+	readers, err := svc.GetMessageReaders(ctx, 3, 100)
+
+	assert.NoError(t, err)
+	assert.Len(t, readers, 1)
+	assert.Equal(t, int64(2), readers[0].UserID)
+}
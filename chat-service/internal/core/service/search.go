@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchMessages runs a full-text search over the conversations userID
+// participates in. query.Text uses web search syntax ("quoted phrases",
+// -excluded words, or). Hits come newest first; cursor continues from the
+// previous page.
+func (s *ChatService) SearchMessages(ctx context.Context, userID int64, query domain.SearchQuery, cursor string) (*domain.SearchPage, error) {
+	query.Text = strings.TrimSpace(query.Text)
+	if query.Text == "" {
+		return nil, ErrEmptySearch
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, ErrInvalidDateRange
+	}
+
+	position, err := domain.DecodePageCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	query.Cursor = position
+	query.ViewerID = userID
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	// Searching a conversation the caller is not in is an error rather than
+	// an empty result, like reading its history
+	if query.ConversationID != nil {
+		if _, err := s.authorize(ctx, userID, *query.ConversationID, ActionReadHistory); err != nil {
+			return nil, err
+		}
+	}
+
+	// Ask for one extra hit to know if there is a next page
+	query.Limit = limit + 1
+	hits, err := s.repo.SearchMessages(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.SearchPage{Hits: hits}
+	if page.Hits == nil {
+		page.Hits = []domain.SearchHit{}
+	}
+	if len(hits) > limit {
+		page.Hits = hits[:limit]
+		page.NextCursor = messageCursor(page.Hits[limit-1].Message).Encode()
+	}
+	return page, nil
+}
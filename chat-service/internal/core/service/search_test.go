@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+func TestSearchMessages_PagesWithCursor(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	now := time.Now()
+	hits := []domain.SearchHit{
+		{Message: domain.Message{ID: 3, CreatedAt: now}, Snippet: "<mark>lunch</mark> today"},
+		{Message: domain.Message{ID: 2, CreatedAt: now.Add(-time.Minute)}},
+		{Message: domain.Message{ID: 1, CreatedAt: now.Add(-2 * time.Minute)}},
+	}
+	repo.On("SearchMessages", mock.Anything, mock.MatchedBy(func(q domain.SearchQuery) bool {
+		return q.ViewerID == 5 && q.Text == "lunch" && q.Limit == 3 && q.Cursor == nil
+	})).Return(hits, nil)
+
+	page, err := svc.SearchMessages(context.Background(), 5, domain.SearchQuery{Text: "  lunch ", Limit: 2}, "")
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Hits, 2)
+	cursor, err := domain.DecodePageCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), cursor.ID)
+}
+
+func TestSearchMessages_ValidatesInput(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	_, err := svc.SearchMessages(context.Background(), 5, domain.SearchQuery{Text: "   "}, "")
+	assert.ErrorIs(t, err, ErrEmptySearch)
+
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	_, err = svc.SearchMessages(context.Background(), 5, domain.SearchQuery{Text: "x", From: &now, To: &earlier}, "")
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+
+	repo.AssertNotCalled(t, "SearchMessages", mock.Anything, mock.Anything)
+}
+
+func TestSearchMessages_OutsiderCannotFilterByConversation(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	convID := int64(10)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(9)).Return(nil, nil)
+
+	_, err := svc.SearchMessages(context.Background(), 9, domain.SearchQuery{Text: "x", ConversationID: &convID}, "")
+
+	assert.ErrorIs(t, err, ErrNotParticipant)
+	repo.AssertNotCalled(t, "SearchMessages", mock.Anything, mock.Anything)
+}
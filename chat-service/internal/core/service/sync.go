@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+const (
+	defaultSyncLimit = 100
+	maxSyncLimit     = 500
+)
+
+// Sync returns the messages userID missed in all of their conversations
+// since the given cursor, together with the cursor to use next time.
+// At most limit messages are returned per conversation.
+func (s *ChatService) Sync(ctx context.Context, userID int64, cursor string, limit int) (*domain.SyncResult, error) {
+	current, err := domain.DecodeSyncCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultSyncLimit
+	}
+	if limit > maxSyncLimit {
+		limit = maxSyncLimit
+	}
+
+	// Ask for one extra message per conversation to know if there is more
+	messages, err := s.repo.GetMessagesSince(ctx, userID, current, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.SyncResult{Messages: make([]domain.Message, 0, len(messages))}
+	perConversation := make(map[int64]int)
+	for _, msg := range messages {
+		if perConversation[msg.ConversationID] == limit {
+			result.HasMore = true
+			continue
+		}
+		perConversation[msg.ConversationID]++
+
+		result.Messages = append(result.Messages, msg)
+		if msg.Seq > current[msg.ConversationID] {
+			current[msg.ConversationID] = msg.Seq
+		}
+	}
+
+	result.Cursor = current.Encode()
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+func TestSync_AdvancesCursorAndReportsMore(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	start := domain.SyncCursor{10: 4}
+
+	// limit 2 -> repo is asked for 3 per conversation
+	repo.On("GetMessagesSince", mock.Anything, int64(1), start, 3).
+		Return([]domain.Message{
+			{ID: 1, ConversationID: 10, Seq: 5},
+			{ID: 2, ConversationID: 10, Seq: 6},
+			{ID: 3, ConversationID: 10, Seq: 7},
+			{ID: 4, ConversationID: 20, Seq: 1},
+		}, nil)
+
+	result, err := svc.Sync(ctx, 1, start.Encode(), 2)
+
+	assert.NoError(t, err)
+	assert.True(t, result.HasMore)
+	assert.Len(t, result.Messages, 3)
+
+	next, err := domain.DecodeSyncCursor(result.Cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.SyncCursor{10: 6, 20: 1}, next)
+
+	repo.AssertExpectations(t)
+}
+
+func TestSync_InvalidCursor(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+
+	result, err := svc.Sync(context.Background(), 1, "%%%", 0)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
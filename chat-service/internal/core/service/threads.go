@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+// Reply answers parentID in its conversation. With inThread the reply goes
+// to the parent's thread (starting one if needed) and only the thread's
+// participants are notified; otherwise it quotes the parent inline and
+// behaves like any other message. A quote of a thread reply stays in that
+// thread. Attachments and retries are handled like in SendMessage.
+func (s *ChatService) Reply(ctx context.Context, senderID, parentID int64, content, clientMessageID string, inThread bool, attachmentIDs ...int64) (*domain.Message, error) {
+	if strings.TrimSpace(content) == "" && len(attachmentIDs) == 0 {
+		return nil, ErrEmptyContent
+	}
+	if !validClientMessageID(clientMessageID) {
+		return nil, ErrInvalidClientMessageID
+	}
+
+	parent, err := s.repo.GetMessageByID(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil || parent.DeletedAt != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if _, err := s.authorize(ctx, senderID, parent.ConversationID, ActionSendMessage); err != nil {
+		return nil, err
+	}
+
+	original, err := s.previousSend(ctx, parent.ConversationID, senderID, clientMessageID)
+	if err != nil {
+		return nil, err
+	}
+	if original != nil {
+		return original, nil
+	}
+
+	attachments, err := s.pendingAttachments(ctx, senderID, attachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &domain.Message{
+		ConversationID:  parent.ConversationID,
+		SenderID:        senderID,
+		Content:         content,
+		ThreadID:        parent.ThreadID,
+		ClientMessageID: clientMessageIDPtr(clientMessageID),
+		CreatedAt:       time.Now(),
+	}
+	if !inThread {
+		msg.ReplyToID = &parent.ID
+	} else if msg.ThreadID == nil {
+		msg.ThreadID = &parent.ID
+	}
+
+	stored, duplicate, err := s.saveMessage(ctx, msg, attachments)
+	if err != nil || duplicate {
+		return stored, err
+	}
+
+	if msg.ThreadID != nil {
+		s.notifyThread(ctx, msg)
+		return msg, nil
+	}
+
+	parts, err := s.repo.GetParticipants(ctx, msg.ConversationID)
+	if err != nil {
+		// The message is stored, recipients will see it in their history
+		return msg, nil
+	}
+	recipients := make([]int64, 0, len(parts))
+	for _, p := range parts {
+		if p.UserID != senderID {
+			recipients = append(recipients, p.UserID)
+		}
+	}
+	s.deliver(ctx, recipients, websocket.EventMessage, msg)
+
+	return msg, nil
+}
+
+// GetThread returns one page of the replies to a root message, oldest
+// first unless asked otherwise
+func (s *ChatService) GetThread(ctx context.Context, userID, rootID int64, cursor string, direction domain.Direction, limit int) (*domain.MessagePage, error) {
+	if direction == "" {
+		direction = domain.DirectionForward
+	}
+	query, err := historyQuery(userID, cursor, direction, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := s.repo.GetMessageByID(ctx, rootID)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if _, err := s.authorize(ctx, userID, root.ConversationID, ActionReadHistory); err != nil {
+		return nil, err
+	}
+
+	// Replies always hang off the root, asking for a reply's thread means
+	// asking for the thread it is in
+	if root.ThreadID != nil {
+		rootID = *root.ThreadID
+	}
+	query.ThreadID = &rootID
+
+	return s.messagePage(ctx, root.ConversationID, query)
+}
+
+// notifyThread pushes a thread reply to everyone who took part in the
+// thread and is still in the conversation, except its sender
+func (s *ChatService) notifyThread(ctx context.Context, msg *domain.Message) {
+	userIDs, err := s.repo.GetThreadParticipants(ctx, *msg.ThreadID)
+	if err != nil {
+		return
+	}
+	parts, err := s.repo.GetParticipants(ctx, msg.ConversationID)
+	if err != nil {
+		return
+	}
+
+	recipients := make([]int64, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if userID != msg.SenderID && findParticipant(parts, userID) != nil {
+			recipients = append(recipients, userID)
+		}
+	}
+	s.deliver(ctx, recipients, websocket.EventThreadReply, msg)
+}
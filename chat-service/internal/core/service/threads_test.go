@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+func TestReply_InThreadNotifiesThreadParticipantsOnly(t *testing.T) {
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("SaveMessage", mock.Anything, mock.MatchedBy(func(m *domain.Message) bool {
+		return m.ThreadID != nil && *m.ThreadID == 100 && m.ReplyToID == nil
+	})).Return(nil)
+	// User 4 replied earlier but has left the group since
+	repo.On("GetThreadParticipants", mock.Anything, int64(100)).Return([]int64{1, 2, 4}, nil)
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{{UserID: 1}, {UserID: 2}, {UserID: 3}}, nil)
+
+	msg, err := svc.Reply(context.Background(), 2, 100, "agreed", "", true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), msg.ConversationID)
+	assert.Equal(t, []string{websocket.EventThreadReply}, bus.Events())
+	repo.AssertExpectations(t)
+}
+
+func TestReply_QuoteOfThreadReplyStaysInThread(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	root := int64(100)
+	repo.On("GetMessageByID", mock.Anything, int64(105)).
+		Return(&domain.Message{ID: 105, ConversationID: 10, SenderID: 1, ThreadID: &root}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("SaveMessage", mock.Anything, mock.Anything).Return(nil)
+	repo.On("GetThreadParticipants", mock.Anything, root).Return([]int64{1}, nil)
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{{UserID: 1}, {UserID: 2}}, nil)
+
+	msg, err := svc.Reply(context.Background(), 2, 105, "this one", "", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(105), *msg.ReplyToID)
+	assert.Equal(t, root, *msg.ThreadID)
+}
+
+func TestReply_OutsiderIsForbidden(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(9)).Return(nil, nil)
+
+	_, err := svc.Reply(context.Background(), 9, 100, "hi", "", true)
+
+	assert.ErrorIs(t, err, ErrNotParticipant)
+	repo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
+
+func TestGetThread_PagesRepliesOfRoot(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1, ReplyCount: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("GetMessages", mock.Anything, int64(10), mock.MatchedBy(func(q domain.HistoryQuery) bool {
+		return q.ThreadID != nil && *q.ThreadID == 100 && q.Direction == domain.DirectionForward
+	})).Return([]domain.Message{{ID: 101, ConversationID: 10}}, nil)
+
+	page, err := svc.GetThread(context.Background(), 2, 100, "", "", 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Messages, 1)
+	repo.AssertExpectations(t)
+}
+
+func TestReply_RetryReturnsStoredReply(t *testing.T) {
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+
+	clientID := "c-1"
+	root := int64(100)
+	stored := &domain.Message{ID: 101, ConversationID: 10, SenderID: 2, ThreadID: &root, ClientMessageID: &clientID}
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("GetMessageByClientID", mock.Anything, int64(10), int64(2), clientID).Return(stored, nil)
+
+	msg, err := svc.Reply(context.Background(), 2, 100, "agreed", clientID, true)
+
+	assert.NoError(t, err)
+	assert.Same(t, stored, msg)
+	assert.Empty(t, bus.Events())
+	repo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
+
+func TestReply_ConcurrentDuplicateDeliversNothing(t *testing.T) {
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+
+	clientID := "c-1"
+	root := int64(100)
+	winner := &domain.Message{ID: 101, ConversationID: 10, SenderID: 2, ThreadID: &root, ClientMessageID: &clientID}
+	repo.On("GetMessageByID", mock.Anything, int64(100)).
+		Return(&domain.Message{ID: 100, ConversationID: 10, SenderID: 1}, nil)
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(2)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 2, Role: domain.RoleMember}, nil)
+	repo.On("GetMessageByClientID", mock.Anything, int64(10), int64(2), clientID).Return(nil, nil).Once()
+	repo.On("SaveMessage", mock.Anything, mock.Anything).Return(ports.ErrDuplicateMessage)
+	repo.On("GetMessageByClientID", mock.Anything, int64(10), int64(2), clientID).Return(winner, nil).Once()
+
+	msg, err := svc.Reply(context.Background(), 2, 100, "agreed", clientID, true)
+
+	assert.NoError(t, err)
+	assert.Same(t, winner, msg)
+	assert.Empty(t, bus.Events())
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+const (
+	thumbnailMaxSide  = 320
+	thumbnailMimeType = "image/jpeg"
+	// thumbnailMaxPixels refuses to decode huge images (decompression bombs)
+	thumbnailMaxPixels = 40_000_000
+)
+
+// makeThumbnail returns a JPEG thumbnail of an image attachment, or nil if
+// the MIME type is not an image format we can decode
+func makeThumbnail(mimeType string, data []byte) ([]byte, error) {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, nil
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > thumbnailMaxPixels {
+		return nil, fmt.Errorf("image too large for a thumbnail: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleDown(src, thumbnailMaxSide), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleDown fits src in a maxSide square keeping its aspect ratio. Each
+// target pixel averages the source pixels it covers (box filter).
+func scaleDown(src image.Image, maxSide int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSide && h <= maxSide {
+		return src
+	}
+
+	dw, dh := maxSide, h*maxSide/w
+	if h > w {
+		dw, dh = w*maxSide/h, maxSide
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+(y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+(x+1)*w/dw
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+)
+
+// defaultTypingTimeout is how long a typing indicator lives without the
+// client refreshing it
+const defaultTypingTimeout = 6 * time.Second
+
+type typingKey struct {
+	conversationID int64
+	userID         int64
+}
+
+// typingTracker remembers who is typing where, in memory only. Each entry
+// expires on its own if the client stops refreshing it.
+type typingTracker struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	timers  map[typingKey]*time.Timer
+}
+
+func newTypingTracker(timeout time.Duration) *typingTracker {
+	return &typingTracker{
+		timeout: timeout,
+		timers:  make(map[typingKey]*time.Timer),
+	}
+}
+
+// SetTypingTimeout changes how long typing indicators live without a refresh
+func (s *ChatService) SetTypingTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	s.typing.mu.Lock()
+	defer s.typing.mu.Unlock()
+	s.typing.timeout = timeout
+}
+
+// NotifyTyping handles a typing signal from userID. typing=true starts or
+// refreshes the indicator, typing=false stops it. The other participants
+// get typing_started and typing_stopped events; nothing is persisted.
+func (s *ChatService) NotifyTyping(ctx context.Context, userID, conversationID int64, typing bool) error {
+	if typing {
+		return s.startTyping(ctx, userID, conversationID)
+	}
+	s.stopTyping(ctx, userID, conversationID)
+	return nil
+}
+
+func (s *ChatService) startTyping(ctx context.Context, userID, conversationID int64) error {
+	key := typingKey{conversationID: conversationID, userID: userID}
+
+	// Refreshing an active indicator only pushes its expiry back
+	s.typing.mu.Lock()
+	if timer, ok := s.typing.timers[key]; ok {
+		timer.Reset(s.typing.timeout)
+		s.typing.mu.Unlock()
+		return nil
+	}
+	s.typing.mu.Unlock()
+
+	if _, err := s.authorize(ctx, userID, conversationID, ActionTyping); err != nil {
+		return err
+	}
+
+	s.typing.mu.Lock()
+	if timer, ok := s.typing.timers[key]; ok {
+		// Another signal won the race while we were checking membership
+		timer.Reset(s.typing.timeout)
+		s.typing.mu.Unlock()
+		return nil
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(s.typing.timeout, func() {
+		s.typing.mu.Lock()
+		current, ok := s.typing.timers[key]
+		if !ok || current != timer {
+			s.typing.mu.Unlock()
+			return
+		}
+		delete(s.typing.timers, key)
+		s.typing.mu.Unlock()
+
+		// The client went silent, stop the indicator on its behalf
+		s.broadcastTyping(context.Background(), key, websocket.EventTypingStopped)
+	})
+	s.typing.timers[key] = timer
+	s.typing.mu.Unlock()
+
+	s.broadcastTyping(ctx, key, websocket.EventTypingStarted)
+	return nil
+}
+
+func (s *ChatService) stopTyping(ctx context.Context, userID, conversationID int64) {
+	key := typingKey{conversationID: conversationID, userID: userID}
+
+	s.typing.mu.Lock()
+	timer, ok := s.typing.timers[key]
+	if ok {
+		timer.Stop()
+		delete(s.typing.timers, key)
+	}
+	s.typing.mu.Unlock()
+
+	if ok {
+		s.broadcastTyping(ctx, key, websocket.EventTypingStopped)
+	}
+}
+
+// broadcastTyping sends a typing event through the delivery bus to every
+// participant but the typist
+func (s *ChatService) broadcastTyping(ctx context.Context, key typingKey, eventType string) {
+	parts, err := s.repo.GetParticipants(ctx, key.conversationID)
+	if err != nil {
+		return
+	}
+
+	recipients := make([]int64, 0, len(parts))
+	for _, p := range parts {
+		if p.UserID != key.userID {
+			recipients = append(recipients, p.UserID)
+		}
+	}
+
+	s.deliver(ctx, recipients, eventType, websocket.TypingPayload{
+		ConversationID: key.conversationID,
+		UserID:         key.userID,
+		Typing:         eventType == websocket.EventTypingStarted,
+	})
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+// recordingBus remembers the type of every published event
+type recordingBus struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (b *recordingBus) Publish(ctx context.Context, userIDs []int64, payload []byte) error {
+	var env websocket.Envelope
+	json.Unmarshal(payload, &env)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, env.Type)
+	return nil
+}
+
+func (b *recordingBus) Events() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.events...)
+}
+
+func TestNotifyTyping_ExpiresWhenClientGoesSilent(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockRepo)
+	bus := &recordingBus{}
+	svc := NewChatService(repo, bus)
+	svc.SetTypingTimeout(50 * time.Millisecond)
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleMember}, nil).Once()
+	repo.On("GetParticipants", mock.Anything, int64(10)).
+		Return([]domain.Participant{{UserID: 1}, {UserID: 2}}, nil)
+
+	assert.NoError(t, svc.NotifyTyping(ctx, 1, 10, true))
+	// A refresh neither re-checks membership nor re-announces
+	assert.NoError(t, svc.NotifyTyping(ctx, 1, 10, true))
+
+	assert.Eventually(t, func() bool {
+		return len(bus.Events()) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{websocket.EventTypingStarted, websocket.EventTypingStopped}, bus.Events())
+
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
+
+func TestNotifyTyping_NotParticipant(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, &recordingBus{})
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(5)).
+		Return(nil, nil)
+
+	err := svc.NotifyTyping(context.Background(), 5, 10, true)
+
+	assert.ErrorIs(t, err, ErrNotParticipant)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/ports"
+)
+
+// SetUserDirectory makes the service check recipients and new group
+// members against the user service, and show names and avatars in the
+// conversation list. Without one, every user id is taken at face value.
+func (s *ChatService) SetUserDirectory(users ports.UserDirectory) {
+	s.users = users
+}
+
+// requireUsers fails with ErrUserNotFound unless every listed user exists.
+// When the user service cannot be reached the request fails too, rather
+// than letting messages through to users that may not exist.
+func (s *ChatService) requireUsers(ctx context.Context, userIDs ...int64) error {
+	if s.users == nil || len(userIDs) == 0 {
+		return nil
+	}
+
+	if len(userIDs) == 1 {
+		exists, err := s.users.UserExists(ctx, userIDs[0])
+		if err != nil {
+			log.Printf("User lookup failed: %v", err)
+			return ErrUserLookupFailed
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+		return nil
+	}
+
+	profiles, err := s.users.GetUsers(ctx, userIDs)
+	if err != nil {
+		log.Printf("User lookup failed: %v", err)
+		return ErrUserLookupFailed
+	}
+	for _, userID := range userIDs {
+		if _, ok := profiles[userID]; !ok {
+			return ErrUserNotFound
+		}
+	}
+	return nil
+}
+
+// fillProfiles adds names and avatars to the participants of inbox entries.
+// The conversation list still works without them, so lookup errors are
+// only logged.
+func (s *ChatService) fillProfiles(ctx context.Context, entries []domain.InboxEntry) {
+	if s.users == nil {
+		return
+	}
+
+	seen := make(map[int64]bool)
+	var userIDs []int64
+	for _, entry := range entries {
+		for _, member := range entry.Participants {
+			if !seen[member.UserID] {
+				seen[member.UserID] = true
+				userIDs = append(userIDs, member.UserID)
+			}
+		}
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	profiles, err := s.users.GetUsers(ctx, userIDs)
+	if err != nil {
+		log.Printf("Failed to load participant profiles: %v", err)
+		return
+	}
+
+	for i := range entries {
+		for j := range entries[i].Participants {
+			member := &entries[i].Participants[j]
+			if profile, ok := profiles[member.UserID]; ok {
+				member.Username = profile.Username
+				member.DisplayName = profile.DisplayName
+				member.AvatarURL = profile.AvatarURL
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/zhanserikAmangeldi/chat-service/internal/adapters/websocket"
+	"github.com/zhanserikAmangeldi/chat-service/internal/core/domain"
+)
+
+// fakeDirectory is a UserDirectory over a fixed set of profiles
+type fakeDirectory struct {
+	profiles map[int64]domain.UserProfile
+	err      error
+}
+
+func (d *fakeDirectory) UserExists(ctx context.Context, userID int64) (bool, error) {
+	if d.err != nil {
+		return false, d.err
+	}
+	_, ok := d.profiles[userID]
+	return ok, nil
+}
+
+func (d *fakeDirectory) GetUsers(ctx context.Context, userIDs []int64) (map[int64]domain.UserProfile, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	found := make(map[int64]domain.UserProfile)
+	for _, userID := range userIDs {
+		if profile, ok := d.profiles[userID]; ok {
+			found[userID] = profile
+		}
+	}
+	return found, nil
+}
+
+func TestSendMessage_UnknownRecipient(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+	svc.SetUserDirectory(&fakeDirectory{profiles: map[int64]domain.UserProfile{1: {ID: 1}}})
+
+	msg, err := svc.SendMessage(context.Background(), 1, 2, "hello", "")
+
+	assert.Nil(t, msg)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	repo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
+
+func TestSendMessage_UserServiceDown(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+	svc.SetUserDirectory(&fakeDirectory{err: errors.New("connection refused")})
+
+	_, err := svc.SendMessage(context.Background(), 1, 2, "hello", "")
+
+	assert.ErrorIs(t, err, ErrUserLookupFailed)
+	repo.AssertNotCalled(t, "SaveMessage", mock.Anything, mock.Anything)
+}
+
+func TestAddMembers_UnknownUser(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+	svc.SetUserDirectory(&fakeDirectory{profiles: map[int64]domain.UserProfile{1: {ID: 1}, 2: {ID: 2}}})
+
+	repo.On("GetParticipant", mock.Anything, int64(10), int64(1)).
+		Return(&domain.Participant{ConversationID: 10, UserID: 1, Role: domain.RoleOwner}, nil)
+	repo.On("GetConversationByID", mock.Anything, int64(10)).
+		Return(&domain.Conversation{ID: 10, IsGroup: true}, nil)
+
+	added, err := svc.AddMembers(context.Background(), 1, 10, []int64{2, 3})
+
+	assert.Nil(t, added)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	repo.AssertNotCalled(t, "AddParticipant", mock.Anything, mock.Anything)
+}
+
+func TestListConversations_FillsProfiles(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+	svc.SetUserDirectory(&fakeDirectory{profiles: map[int64]domain.UserProfile{
+		2: {ID: 2, Username: "bob", DisplayName: "Bob", AvatarURL: "https://cdn/bob.png"},
+	}})
+
+	repo.On("GetInbox", mock.Anything, int64(1), (*domain.PageCursor)(nil), defaultInboxLimit+1).
+		Return([]domain.InboxEntry{{Conversation: domain.Conversation{ID: 30}, LastActivityAt: time.Now()}}, nil)
+	repo.On("GetParticipantsByConversations", mock.Anything, []int64{30}).
+		Return([]domain.Participant{
+			{ConversationID: 30, UserID: 1, Role: domain.RoleMember},
+			{ConversationID: 30, UserID: 2, Role: domain.RoleMember},
+			{ConversationID: 30, UserID: 3, Role: domain.RoleMember},
+		}, nil)
+
+	page, err := svc.ListConversations(context.Background(), 1, "", 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.InboxMember{
+		{UserID: 2, Role: domain.RoleMember, Username: "bob", DisplayName: "Bob", AvatarURL: "https://cdn/bob.png"},
+		// Deleted users keep their bare id
+		{UserID: 3, Role: domain.RoleMember},
+	}, page.Conversations[0].Participants)
+}
+
+func TestListConversations_WorksWithoutUserService(t *testing.T) {
+	repo := new(MockRepo)
+	svc := NewChatService(repo, websocket.NewClientManager())
+	svc.SetUserDirectory(&fakeDirectory{err: errors.New("connection refused")})
+
+	repo.On("GetInbox", mock.Anything, int64(1), (*domain.PageCursor)(nil), defaultInboxLimit+1).
+		Return([]domain.InboxEntry{{Conversation: domain.Conversation{ID: 30}, LastActivityAt: time.Now()}}, nil)
+	repo.On("GetParticipantsByConversations", mock.Anything, []int64{30}).
+		Return([]domain.Participant{{ConversationID: 30, UserID: 2, Role: domain.RoleMember}}, nil)
+
+	page, err := svc.ListConversations(context.Background(), 1, "", 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.InboxMember{{UserID: 2, Role: domain.RoleMember}}, page.Conversations[0].Participants)
+}
@@ -1,77 +1,24 @@
 package middleware
 
 import (
-	"context"
-	"fmt"
 	"net/http"
-	"strconv"
-	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhanserikAmangeldi/jwtauth"
+	"github.com/zhanserikAmangeldi/jwtauth/httpauth"
 )
 
-type contextKey string
-
-const UserIDKey contextKey = "user_id"
-
-// Simple AuthMiddleware - Only validates JWT signature, no Redis session check
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Extract token from Authorization header
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				http.Error(w, "missing authorization header", http.StatusUnauthorized)
-				return
-			}
-
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			if tokenString == authHeader {
-				http.Error(w, "invalid authorization format", http.StatusUnauthorized)
-				return
-			}
-
-			// Parse and validate JWT token
-			token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-				if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-				}
-				return []byte(jwtSecret), nil
-			})
-
-			if err != nil || !token.Valid {
-				http.Error(w, "invalid token", http.StatusUnauthorized)
-				return
-			}
-
-			// Extract claims
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
-				http.Error(w, "invalid token claims", http.StatusUnauthorized)
-				return
-			}
-
-			// Get user_id from claims
-			userIDFloat, ok := claims["user_id"].(float64)
-			if !ok {
-				http.Error(w, "invalid user_id in token", http.StatusUnauthorized)
-				return
-			}
-			userID := int64(userIDFloat)
-
-			// Add user_id to request context
-			ctx := context.WithValue(r.Context(), UserIDKey, userID)
-
-			// Also set as header for backward compatibility
-			r.Header.Set("X-User-ID", strconv.FormatInt(userID, 10))
-
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
-	}
+// AuthMiddleware accepts access tokens issued by the user service and
+// rejects tokens revoked by logout. revocations may be nil to skip the
+// revocation check.
+func AuthMiddleware(verifier *jwtauth.Verifier, revocations *RevocationList) func(http.Handler) http.Handler {
+	return httpauth.Middleware(verifier, revocations.Checks()...)
 }
 
 // GetUserID extracts user ID from request context
 func GetUserID(r *http.Request) (int64, bool) {
-	userID, ok := r.Context().Value(UserIDKey).(int64)
-	return userID, ok
+	claims, ok := httpauth.ClaimsFrom(r.Context())
+	if !ok {
+		return 0, false
+	}
+	return claims.UserID, true
 }
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhanserikAmangeldi/jwtauth"
+)
+
+// RevocationList checks access tokens with jwtauth.NotRevoked and wakes
+// the watchers of tokens the user service announces as revoked.
+//
+// A nil *RevocationList accepts every token.
+type RevocationList struct {
+	client *redis.Client
+	check  jwtauth.Check
+
+	mu       sync.Mutex
+	watchers map[string]map[chan struct{}]struct{}
+}
+
+func NewRevocationList(client *redis.Client, failOpen bool) *RevocationList {
+	return &RevocationList{
+		client:   client,
+		check:    jwtauth.NotRevoked(client, failOpen),
+		watchers: make(map[string]map[chan struct{}]struct{}),
+	}
+}
+
+// Check returns jwtauth.ErrTokenRevoked for revoked tokens, and
+// jwtauth.ErrRevocationUnavailable when Redis is down and the list fails
+// closed
+func (l *RevocationList) Check(ctx context.Context, token string) error {
+	if l == nil {
+		return nil
+	}
+	return l.check(ctx, token, nil)
+}
+
+// Checks returns the revocation check in the form token verification takes
+// it, none for a nil list
+func (l *RevocationList) Checks() []jwtauth.Check {
+	if l == nil {
+		return nil
+	}
+	return []jwtauth.Check{l.check}
+}
+
+// Watch returns a channel that is closed when token is announced as
+// revoked. stop must be called once the token is no longer in use.
+func (l *RevocationList) Watch(token string) (revoked <-chan struct{}, stop func()) {
+	if l == nil {
+		return nil, func() {}
+	}
+
+	ch := make(chan struct{})
+	l.mu.Lock()
+	if l.watchers[token] == nil {
+		l.watchers[token] = make(map[chan struct{}]struct{})
+	}
+	l.watchers[token][ch] = struct{}{}
+	l.mu.Unlock()
+
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if watchers, ok := l.watchers[token]; ok {
+			delete(watchers, ch)
+			if len(watchers) == 0 {
+				delete(l.watchers, token)
+			}
+		}
+	}
+}
+
+// Run wakes the watchers of tokens announced on jwtauth.RevokedChannel until ctx is
+// cancelled. Announcements missed while Redis is unreachable are lost, so
+// long-lived connections should also Check their token now and then.
+func (l *RevocationList) Run(ctx context.Context) error {
+	sub := l.client.Subscribe(ctx, jwtauth.RevokedChannel)
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			l.revoked(msg.Payload)
+		}
+	}
+}
+
+func (l *RevocationList) revoked(token string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.watchers[token] {
+		close(ch)
+	}
+	delete(l.watchers, token)
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhanserikAmangeldi/jwtauth"
+)
+
+func newTestRevocationList(t *testing.T, failOpen bool) (*RevocationList, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return NewRevocationList(client, failOpen), server
+}
+
+func TestRevocationList_Check(t *testing.T) {
+	list, server := newTestRevocationList(t, false)
+	require.NoError(t, server.Set(jwtauth.RevokedKey("old-token"), "1"))
+	ctx := context.Background()
+
+	assert.ErrorIs(t, list.Check(ctx, "old-token"), jwtauth.ErrTokenRevoked)
+	assert.NoError(t, list.Check(ctx, "fresh-token"))
+
+	server.Close()
+	assert.ErrorIs(t, list.Check(ctx, "fresh-token"), jwtauth.ErrRevocationUnavailable)
+}
+
+func TestRevocationList_NilAcceptsEverything(t *testing.T) {
+	var list *RevocationList
+
+	assert.NoError(t, list.Check(context.Background(), "token"))
+	assert.Empty(t, list.Checks())
+
+	revoked, stop := list.Watch("token")
+	assert.Nil(t, revoked)
+	stop()
+}
+
+func TestRevocationList_RunWakesWatchers(t *testing.T) {
+	list, server := newTestRevocationList(t, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	revoked, stop := list.Watch("old-token")
+	defer stop()
+	other, stopOther := list.Watch("other-token")
+	defer stopOther()
+
+	go list.Run(ctx)
+	require.Eventually(t, func() bool {
+		return len(server.PubSubChannels(jwtauth.RevokedChannel)) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	server.Publish(jwtauth.RevokedChannel, "old-token")
+
+	select {
+	case <-revoked:
+	case <-time.After(time.Second):
+		t.Fatal("expected the watcher of the revoked token to be woken")
+	}
+	select {
+	case <-other:
+		t.Fatal("expected other tokens to stay watched")
+	default:
+	}
+}
+
+func TestRevocationList_StoppedWatcherIsForgotten(t *testing.T) {
+	list, _ := newTestRevocationList(t, false)
+
+	_, stop := list.Watch("token")
+	stop()
+	list.revoked("token")
+
+	assert.Empty(t, list.watchers)
+}
@@ -0,0 +1,5 @@
+ALTER TABLE participants DROP COLUMN IF EXISTS role;
+
+ALTER TABLE conversations
+    DROP COLUMN IF EXISTS created_by,
+    DROP COLUMN IF EXISTS title;
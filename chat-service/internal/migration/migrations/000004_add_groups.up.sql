@@ -0,0 +1,6 @@
+ALTER TABLE conversations
+    ADD COLUMN title TEXT,
+    ADD COLUMN created_by BIGINT;
+
+ALTER TABLE participants
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'member';
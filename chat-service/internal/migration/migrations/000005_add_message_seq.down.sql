@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_messages_conv_seq;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS seq;
+
+ALTER TABLE conversations DROP COLUMN IF EXISTS last_seq;
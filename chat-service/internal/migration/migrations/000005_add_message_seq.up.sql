@@ -0,0 +1,19 @@
+ALTER TABLE conversations ADD COLUMN last_seq BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE messages ADD COLUMN seq BIGINT;
+
+-- Number existing messages per conversation in the order they were sent
+UPDATE messages m
+SET seq = numbered.seq
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY conversation_id ORDER BY created_at, id) AS seq
+    FROM messages
+) numbered
+WHERE m.id = numbered.id;
+
+UPDATE conversations c
+SET last_seq = COALESCE((SELECT MAX(seq) FROM messages m WHERE m.conversation_id = c.id), 0);
+
+ALTER TABLE messages ALTER COLUMN seq SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_messages_conv_seq ON messages(conversation_id, seq);
@@ -0,0 +1,3 @@
+ALTER TABLE participants
+    DROP COLUMN IF EXISTS read_seq,
+    DROP COLUMN IF EXISTS delivered_seq;
@@ -0,0 +1,5 @@
+-- Per-participant watermarks: every message with seq <= the watermark has
+-- been delivered to / read by the participant
+ALTER TABLE participants
+    ADD COLUMN delivered_seq BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN read_seq BIGINT NOT NULL DEFAULT 0;
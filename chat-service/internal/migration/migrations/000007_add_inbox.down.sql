@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_participants_inbox;
+
+ALTER TABLE participants
+    DROP COLUMN IF EXISTS pinned,
+    DROP COLUMN IF EXISTS muted_until,
+    DROP COLUMN IF EXISTS last_activity_at;
+
+ALTER TABLE conversations DROP COLUMN IF EXISTS last_message_id;
@@ -0,0 +1,25 @@
+ALTER TABLE conversations ADD COLUMN last_message_id BIGINT;
+
+UPDATE conversations c
+SET last_message_id = latest.id
+FROM (
+    SELECT DISTINCT ON (conversation_id) id, conversation_id
+    FROM messages
+    ORDER BY conversation_id, seq DESC
+) latest
+WHERE latest.conversation_id = c.id;
+
+-- Inbox state is kept per participant so a user's chat list is served
+-- straight from the (user_id, last_activity_at) index
+ALTER TABLE participants
+    ADD COLUMN last_activity_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ADD COLUMN muted_until TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE participants p
+SET last_activity_at = COALESCE(m.created_at, c.created_at, p.joined_at)
+FROM conversations c
+LEFT JOIN messages m ON m.id = c.last_message_id
+WHERE c.id = p.conversation_id;
+
+CREATE INDEX idx_participants_inbox ON participants(user_id, last_activity_at DESC, conversation_id DESC);
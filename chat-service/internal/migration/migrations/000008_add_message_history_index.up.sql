@@ -0,0 +1,2 @@
+-- History pages seek on (created_at, id) within a conversation
+CREATE INDEX idx_messages_history ON messages(conversation_id, created_at, id);
@@ -0,0 +1,12 @@
+ALTER TABLE messages ADD COLUMN edited_at TIMESTAMP WITH TIME ZONE;
+
+-- Every version a message had before an edit, written_at is when that
+-- version was written (the send or the previous edit)
+CREATE TABLE message_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    written_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_message_revisions_message ON message_revisions(message_id, written_at);
@@ -0,0 +1,11 @@
+-- Deleted-for-everyone messages stay in place as tombstones, so sequences
+-- and history cursors keep pointing at real rows
+ALTER TABLE messages ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Messages a user deleted for themselves only
+CREATE TABLE hidden_messages (
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    hidden_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, message_id)
+);
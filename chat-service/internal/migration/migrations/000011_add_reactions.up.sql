@@ -0,0 +1,9 @@
+-- One row per user, emoji and message: the primary key keeps a user from
+-- adding the same emoji twice
+CREATE TABLE reactions (
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (message_id, user_id, emoji)
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_messages_thread;
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS reply_count,
+    DROP COLUMN IF EXISTS thread_id,
+    DROP COLUMN IF EXISTS reply_to_id;
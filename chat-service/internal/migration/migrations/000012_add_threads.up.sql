@@ -0,0 +1,8 @@
+-- reply_to_id quotes a message inline, thread_id puts a reply in the
+-- thread started by that (root) message. Roots count their replies.
+ALTER TABLE messages
+    ADD COLUMN reply_to_id BIGINT REFERENCES messages(id) ON DELETE SET NULL,
+    ADD COLUMN thread_id BIGINT REFERENCES messages(id) ON DELETE CASCADE,
+    ADD COLUMN reply_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_messages_thread ON messages(thread_id, created_at, id) WHERE thread_id IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Attachments are uploaded first and linked to a message when it is sent;
+-- until then only the uploader can see them
+CREATE TABLE attachments (
+    id BIGSERIAL PRIMARY KEY,
+    uploader_id BIGINT NOT NULL,
+    conversation_id BIGINT REFERENCES conversations(id) ON DELETE CASCADE,
+    message_id BIGINT REFERENCES messages(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    checksum TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_attachments_message ON attachments(message_id) WHERE message_id IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Full-text search over message content. The 'simple' configuration does
+-- no stemming, so it behaves the same for every language users write in.
+-- Queries must use the same to_tsvector expression to hit the index.
+CREATE INDEX idx_messages_search ON messages USING GIN (to_tsvector('simple', content));
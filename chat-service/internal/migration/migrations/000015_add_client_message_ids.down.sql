@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_messages_client_id;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS client_message_id;
@@ -0,0 +1,6 @@
+-- Clients tag each send with their own id so a retried request returns the
+-- message stored by the first attempt instead of a duplicate
+ALTER TABLE messages ADD COLUMN client_message_id TEXT;
+
+CREATE UNIQUE INDEX idx_messages_client_id ON messages(conversation_id, sender_id, client_message_id)
+    WHERE client_message_id IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_conversations_pair;
+
+ALTER TABLE conversations
+    DROP COLUMN IF EXISTS pair_high,
+    DROP COLUMN IF EXISTS pair_low;
@@ -0,0 +1,26 @@
+-- A 1:1 conversation is keyed by its two users, lowest id first, so there
+-- is exactly one per pair whoever writes first
+ALTER TABLE conversations
+    ADD COLUMN pair_low BIGINT,
+    ADD COLUMN pair_high BIGINT;
+
+-- Backfill from participants. Pairs that already ended up with several
+-- conversations keep their oldest one; the others stay readable but new
+-- messages go to the oldest. Conversations one side has left are not
+-- backfilled, they would otherwise pose as a user's chat with themselves.
+UPDATE conversations c
+SET pair_low = pairs.low, pair_high = pairs.high
+FROM (
+    SELECT DISTINCT ON (low, high) conversation_id, low, high
+    FROM (
+        SELECT p.conversation_id, MIN(p.user_id) AS low, MAX(p.user_id) AS high
+        FROM participants p
+        JOIN conversations cc ON cc.id = p.conversation_id AND cc.is_group = false
+        GROUP BY p.conversation_id
+        HAVING COUNT(DISTINCT p.user_id) >= 2
+    ) per_conversation
+    ORDER BY low, high, conversation_id
+) pairs
+WHERE c.id = pairs.conversation_id;
+
+CREATE UNIQUE INDEX idx_conversations_pair ON conversations(pair_low, pair_high) WHERE is_group = false;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_participants_inbox;
+CREATE INDEX idx_participants_inbox ON participants(user_id, last_activity_at DESC, conversation_id DESC);
+
+ALTER TABLE participants DROP COLUMN IF EXISTS unread_count;
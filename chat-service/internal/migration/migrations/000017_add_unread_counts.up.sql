@@ -0,0 +1,23 @@
+-- Unread messages are counted per participant as they arrive, are read,
+-- hidden or deleted, so the inbox does not count them per row. Thread
+-- replies, tombstones, hidden messages and the participant's own messages
+-- never count.
+ALTER TABLE participants ADD COLUMN unread_count INTEGER NOT NULL DEFAULT 0;
+
+UPDATE participants p
+SET unread_count = unread.count
+FROM (
+    SELECT pp.conversation_id, pp.user_id, COUNT(*) AS count
+    FROM participants pp
+    JOIN messages m ON m.conversation_id = pp.conversation_id AND m.seq > pp.read_seq
+    WHERE m.sender_id <> pp.user_id
+    AND m.thread_id IS NULL
+    AND m.deleted_at IS NULL
+    AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.user_id = pp.user_id)
+    GROUP BY pp.conversation_id, pp.user_id
+) unread
+WHERE unread.conversation_id = p.conversation_id AND unread.user_id = p.user_id;
+
+-- Pinned conversations come first in the inbox
+DROP INDEX idx_participants_inbox;
+CREATE INDEX idx_participants_inbox ON participants(user_id, pinned DESC, last_activity_at DESC, conversation_id DESC);
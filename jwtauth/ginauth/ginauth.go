@@ -0,0 +1,43 @@
+// Package ginauth adapts jwtauth to gin
+package ginauth
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/zhanserikAmangeldi/jwtauth"
+	"github.com/zhanserikAmangeldi/jwtauth/httpauth"
+)
+
+const claimsKey = "jwtauth.claims"
+
+// Middleware aborts requests without a valid access token in their
+// Authorization header, or whose token fails a check. The claims are stored
+// in the gin context, see Claims.
+func Middleware(verifier *jwtauth.Verifier, checks ...jwtauth.Check) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := httpauth.BearerToken(c.Request)
+		if err != nil {
+			c.AbortWithStatusJSON(httpauth.Status(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		claims, err := verifier.Authenticate(c.Request.Context(), token, checks...)
+		if err != nil {
+			c.AbortWithStatusJSON(httpauth.Status(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(claimsKey, claims)
+		c.Next()
+	}
+}
+
+// Claims returns the claims Middleware stored in c
+func Claims(c *gin.Context) (*jwtauth.Claims, bool) {
+	value, ok := c.Get(claimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*jwtauth.Claims)
+	return claims, ok
+}
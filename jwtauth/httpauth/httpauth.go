@@ -0,0 +1,74 @@
+// Package httpauth adapts jwtauth to net/http
+package httpauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/zhanserikAmangeldi/jwtauth"
+)
+
+var (
+	ErrMissingToken    = errors.New("missing authorization header")
+	ErrMalformedHeader = errors.New("invalid authorization format")
+)
+
+type contextKey struct{}
+
+// Middleware lets a request through only with a valid access token in its
+// Authorization header that passes every check. The claims are stored in
+// the request context, see ClaimsFrom.
+func Middleware(verifier *jwtauth.Verifier, checks ...jwtauth.Check) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := BearerToken(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := verifier.Authenticate(r.Context(), token, checks...)
+			if err != nil {
+				http.Error(w, err.Error(), Status(err))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// BearerToken reads the token from the Authorization header
+func BearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", ErrMissingToken
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", ErrMalformedHeader
+	}
+	return token, nil
+}
+
+// Status is the HTTP status answering an authentication error: 503 when a
+// check could not be made, 401 otherwise
+func Status(err error) int {
+	if errors.Is(err, jwtauth.ErrUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusUnauthorized
+}
+
+// WithClaims returns a copy of ctx carrying claims
+func WithClaims(ctx context.Context, claims *jwtauth.Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// ClaimsFrom returns the claims Middleware stored in ctx
+func ClaimsFrom(ctx context.Context) (*jwtauth.Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*jwtauth.Claims)
+	return claims, ok
+}
@@ -0,0 +1,237 @@
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWKSPath is where a signing service serves its public keys
+const JWKSPath = "/.well-known/jwks.json"
+
+// JWK is a public key in JSON Web Key form (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func publicJWK(kid, alg string, public crypto.PublicKey) JWK {
+	jwk := JWK{KeyID: kid, Use: "sig", Algorithm: alg}
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// PublicKey decodes the RSA or Ed25519 key
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case j.KeyType == "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent out of range")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case j.KeyType == "OKP" && j.Curve == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.KeyType)
+	}
+}
+
+// JWKSHandler serves the public keys of keyring, letting clients cache
+// them for maxAge
+func JWKSHandler(keyring *Keyring, maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+		json.NewEncoder(w).Encode(keyring.JWKS())
+	})
+}
+
+// minJWKSRefresh limits how often fetches are retried, so tokens with
+// made-up kids or a signing service that is down do not cause a fetch per
+// request
+const minJWKSRefresh = 10 * time.Second
+
+// RemoteKeySet is a KeySet fetched from a JWKS endpoint. The keys are
+// cached for the configured TTL and fetched again early for a kid the cache
+// does not know, which is how keys rotated in are picked up. When a fetch
+// fails the cached keys keep being used.
+//
+// Fetches run without holding the cache: known keys are served while one
+// is in flight, only lookups of unknown kids wait for it, and at most one
+// fetch runs at a time.
+type RemoteKeySet struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	triedAt   time.Time
+	// refreshing is closed when the fetch in flight is done, nil if none is
+	refreshing chan struct{}
+	fetchErr   error
+}
+
+func NewRemoteKeySet(url string, ttl time.Duration) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// PublicKey returns the key with id kid. Errors wrap ErrUnavailable until
+// the JWKS has been fetched once.
+func (s *RemoteKeySet) PublicKey(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	now := time.Now()
+	key, known := s.keys[kid]
+	stale := now.Sub(s.fetchedAt) >= s.ttl
+
+	var done chan struct{}
+	if (stale || !known) && now.Sub(s.triedAt) >= minJWKSRefresh {
+		done = s.startRefresh(now)
+	} else if !known {
+		// A fetch already in flight may bring the kid
+		done = s.refreshing
+	}
+	s.mu.Unlock()
+
+	if known {
+		return key, nil
+	}
+	if done != nil {
+		<-done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		return nil, fmt.Errorf("JWKS not fetched yet: %w", ErrUnavailable)
+	}
+	if key, known = s.keys[kid]; !known {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// Refresh fetches the keys now, e.g. to warm the cache at startup
+func (s *RemoteKeySet) Refresh() error {
+	s.mu.Lock()
+	done := s.startRefresh(time.Now())
+	s.mu.Unlock()
+
+	<-done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetchErr
+}
+
+// startRefresh starts a fetch unless one is in flight and returns the
+// channel closed once it is done. s.mu must be held.
+func (s *RemoteKeySet) startRefresh(now time.Time) chan struct{} {
+	if s.refreshing != nil {
+		return s.refreshing
+	}
+	s.triedAt = now
+	done := make(chan struct{})
+	s.refreshing = done
+
+	go func() {
+		keys, err := s.fetch()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err != nil {
+			log.Printf("Failed to refresh JWKS, using cached keys: %v", err)
+		} else {
+			s.keys = keys
+			s.fetchedAt = time.Now()
+		}
+		s.fetchErr = err
+		s.refreshing = nil
+		close(done)
+	}()
+	return done
+}
+
+// fetch downloads the key set; it runs without s.mu held
+func (s *RemoteKeySet) fetch() (map[string]crypto.PublicKey, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			log.Printf("Skipping JWKS key %q: %v", jwk.KeyID, err)
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
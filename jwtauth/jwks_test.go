@@ -0,0 +1,157 @@
+package jwtauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRemoteKeySet_VerifiesWithFetchedKeys(t *testing.T) {
+	for _, alg := range []string{AlgEdDSA, AlgRS256} {
+		keyring := newTestKeyring(t, alg)
+		server := httptest.NewServer(JWKSHandler(keyring, time.Minute))
+
+		token, _, err := NewSigner(Config{Keys: keyring}).Sign(7, "alice", "", TokenAccess, time.Hour)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", alg, err)
+		}
+		verifier := NewVerifier(Config{Keys: NewRemoteKeySet(server.URL+JWKSPath, time.Minute)})
+		if _, err := verifier.Verify(token, TokenAccess); err != nil {
+			t.Errorf("%s: unexpected validation error: %v", alg, err)
+		}
+		server.Close()
+	}
+}
+
+func TestRemoteKeySet_CachesAndPicksUpRotatedKeys(t *testing.T) {
+	keyring := newTestKeyring(t, AlgEdDSA)
+	var fetches atomic.Int32
+	handler := JWKSHandler(keyring, time.Minute)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL, time.Hour)
+	verifier := NewVerifier(Config{Keys: keys})
+	signer := NewSigner(Config{Keys: keyring})
+
+	for i := 0; i < 3; i++ {
+		token, _, err := signer.Sign(7, "alice", "", TokenAccess, time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := verifier.Verify(token, TokenAccess); err != nil {
+			t.Fatalf("unexpected validation error: %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("expected the JWKS to be fetched once, got %d", got)
+	}
+
+	// A kid the cache does not know triggers a fetch
+	next, err := GenerateKey(AlgEdDSA, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := keyring.Add(next); err != nil {
+		t.Fatalf("failed to add key: %v", err)
+	}
+	keys.triedAt = time.Time{}
+
+	token, _, err := signer.Sign(7, "alice", "", TokenAccess, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := verifier.Verify(token, TokenAccess); err != nil {
+		t.Fatalf("expected the rotated key to be fetched, got %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Fatalf("expected a second fetch, got %d", got)
+	}
+}
+
+func TestRemoteKeySet_StalledFetchDoesNotBlockKnownKeys(t *testing.T) {
+	keyring := newTestKeyring(t, AlgEdDSA)
+	handler := JWKSHandler(keyring, time.Minute)
+	stall := make(chan struct{})
+	var stalled atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if stalled.Load() {
+			<-stall
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	defer close(stall)
+
+	keys := NewRemoteKeySet(server.URL, time.Hour)
+	if err := keys.Refresh(); err != nil {
+		t.Fatalf("failed to fetch JWKS: %v", err)
+	}
+	known := keyring.JWKS().Keys[0].KeyID
+
+	// A forged kid starts a fetch that hangs
+	stalled.Store(true)
+	keys.mu.Lock()
+	keys.triedAt = time.Time{}
+	keys.mu.Unlock()
+	forged := make(chan error, 1)
+	go func() {
+		_, err := keys.PublicKey("forged")
+		forged <- err
+	}()
+	fetching := func() bool {
+		keys.mu.Lock()
+		defer keys.mu.Unlock()
+		return keys.refreshing != nil
+	}
+	for !fetching() {
+		time.Sleep(time.Millisecond)
+	}
+
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			_, err := keys.PublicKey(known)
+			results <- err
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("lookups of known keys waited for the stalled fetch")
+		}
+	}
+
+	select {
+	case err := <-forged:
+		t.Fatalf("expected the forged kid to wait for the fetch, got %v", err)
+	default:
+	}
+}
+
+func TestRemoteKeySet_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	token, _, err := NewSigner(Config{Keys: newTestKeyring(t, AlgEdDSA)}).Sign(7, "alice", "", TokenAccess, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	verifier := NewVerifier(Config{Keys: NewRemoteKeySet(server.URL, time.Minute)})
+	if _, err := verifier.Verify(token, TokenAccess); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+// Package jwtauth issues and verifies the JWTs shared by user-service and
+// chat-service. Both services go through it, so the claims cannot drift
+// apart between the one that signs tokens and the ones that read them.
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenType tells access tokens from refresh tokens, so one cannot be used
+// in place of the other
+type TokenType string
+
+const (
+	TokenAccess  TokenType = "access"
+	TokenRefresh TokenType = "refresh"
+	// AnyToken makes Verify accept both types
+	AnyToken TokenType = ""
+)
+
+// Defaults for Config
+const (
+	DefaultIssuer   = "user-service"
+	DefaultAudience = "chat-app"
+)
+
+var (
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrExpiredToken   = errors.New("expired token")
+	ErrWrongTokenType = errors.New("wrong token type")
+	// ErrUnavailable is wrapped by checks that could not reach what they
+	// check tokens against; the middleware adapters answer it with 503
+	ErrUnavailable = errors.New("token check unavailable")
+)
+
+// Claims is the payload of every token
+type Claims struct {
+	UserID   int64     `json:"user_id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	Type     TokenType `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+// Config is shared by signers and verifiers; both sides must agree on it
+type Config struct {
+	// Secret signs and verifies HS256 tokens when Keys is nil
+	Secret string
+	// Keys switches to RS256 or EdDSA tokens naming their key in the kid
+	// header. Signers need a *Keyring; verifiers take any KeySet, e.g. a
+	// RemoteKeySet, and no longer need the secret.
+	Keys     KeySet
+	Issuer   string
+	Audience string
+	// AcceptHS256Until keeps verifiers with Keys accepting HS256 tokens
+	// signed with Secret until then. Set it a refresh-token lifetime past
+	// the switch from Secret to Keys, so tokens issued before the switch
+	// keep working instead of logging everybody out.
+	AcceptHS256Until time.Time
+}
+
+func (c Config) withDefaults() Config {
+	if c.Issuer == "" {
+		c.Issuer = DefaultIssuer
+	}
+	if c.Audience == "" {
+		c.Audience = DefaultAudience
+	}
+	return c
+}
+
+// Signer issues tokens
+type Signer struct {
+	config Config
+}
+
+func NewSigner(config Config) *Signer {
+	return &Signer{config: config.withDefaults()}
+}
+
+// Sign issues a token of the given type for a user, valid for ttl
+func (s *Signer) Sign(userID int64, username, email string, typ TokenType, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	claims := Claims{
+		UserID:   userID,
+		Username: username,
+		Email:    email,
+		Type:     typ,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.config.Issuer,
+			Audience:  jwt.ClaimStrings{s.config.Audience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token, err := s.sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+func (s *Signer) sign(claims Claims) (string, error) {
+	if s.config.Keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.Secret))
+	}
+
+	keyring, ok := s.config.Keys.(*Keyring)
+	if !ok {
+		return "", errors.New("signing needs a keyring")
+	}
+	key, err := keyring.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Verifier checks tokens
+type Verifier struct {
+	config Config
+	parser *jwt.Parser
+}
+
+func NewVerifier(config Config) *Verifier {
+	config = config.withDefaults()
+
+	// Only ever the algorithms of the configured keys, so an RSA public key
+	// cannot be passed off as an HMAC secret
+	methods := []string{jwt.SigningMethodHS256.Alg()}
+	if config.Keys != nil {
+		methods = []string{AlgRS256, AlgEdDSA}
+		if !config.AcceptHS256Until.IsZero() && config.Secret != "" {
+			methods = append(methods, jwt.SigningMethodHS256.Alg())
+		}
+	}
+	return &Verifier{
+		config: config,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(methods),
+			jwt.WithIssuer(config.Issuer),
+			jwt.WithAudience(config.Audience),
+			jwt.WithExpirationRequired(),
+		),
+	}
+}
+
+// Verify checks the signature, issuer, audience and expiry of a token and
+// that it is of type typ (any type for AnyToken). Expired tokens give
+// ErrExpiredToken, other failures ErrInvalidToken or ErrWrongTokenType, and
+// a key set that cannot be fetched an error wrapping ErrUnavailable.
+func (v *Verifier) Verify(tokenString string, typ TokenType) (*Claims, error) {
+	claims := &Claims{}
+	var keyErr error
+	_, err := v.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// The parser only lets HS256 through when the secret verifies it, and
+		// the secret is never handed out for another algorithm
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			if v.config.Keys != nil && !time.Now().Before(v.config.AcceptHS256Until) {
+				return nil, ErrInvalidToken
+			}
+			return []byte(v.config.Secret), nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		var key interface{}
+		key, keyErr = v.config.Keys.PublicKey(kid)
+		return key, keyErr
+	})
+	if err != nil {
+		if errors.Is(keyErr, ErrUnavailable) {
+			return nil, keyErr
+		}
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	if claims.UserID == 0 {
+		return nil, ErrInvalidToken
+	}
+	if typ != AnyToken && claims.Type != typ {
+		return nil, ErrWrongTokenType
+	}
+	return claims, nil
+}
+
+// Check runs on a token once it verified, e.g. against a revocation list.
+// Errors wrapping ErrUnavailable mean the check could not be made.
+type Check func(ctx context.Context, token string, claims *Claims) error
+
+// Authenticate verifies an access token and runs the checks on it, in
+// order. It is what the middleware adapters do for every request.
+func (v *Verifier) Authenticate(ctx context.Context, tokenString string, checks ...Check) (*Claims, error) {
+	claims, err := v.Verify(tokenString, TokenAccess)
+	if err != nil {
+		return nil, err
+	}
+	for _, check := range checks {
+		if err := check(ctx, tokenString, claims); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
@@ -0,0 +1,121 @@
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testConfig = Config{Secret: "shared-secret"}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := NewSigner(testConfig)
+	verifier := NewVerifier(testConfig)
+
+	token, expiresAt, err := signer.Sign(7, "alice", "alice@example.com", TokenAccess, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if time.Until(expiresAt) > 15*time.Minute {
+		t.Errorf("unexpected expiry %v", expiresAt)
+	}
+
+	claims, err := verifier.Verify(token, TokenAccess)
+	if err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	if claims.UserID != 7 || claims.Username != "alice" || claims.Email != "alice@example.com" {
+		t.Errorf("claims mismatch: got %+v", claims)
+	}
+	if claims.Issuer != DefaultIssuer || claims.Type != TokenAccess {
+		t.Errorf("registered claims mismatch: got %+v", claims)
+	}
+}
+
+func TestVerify_WrongType(t *testing.T) {
+	signer := NewSigner(testConfig)
+	verifier := NewVerifier(testConfig)
+
+	refresh, _, err := signer.Sign(7, "alice", "alice@example.com", TokenRefresh, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := verifier.Verify(refresh, TokenAccess); err != ErrWrongTokenType {
+		t.Fatalf("expected ErrWrongTokenType, got %v", err)
+	}
+	if _, err := verifier.Verify(refresh, AnyToken); err != nil {
+		t.Fatalf("expected any type to be accepted, got %v", err)
+	}
+}
+
+func TestVerify_WrongIssuerOrAudience(t *testing.T) {
+	verifier := NewVerifier(testConfig)
+
+	configs := []Config{
+		{Secret: testConfig.Secret, Issuer: "someone-else"},
+		{Secret: testConfig.Secret, Audience: "another-app"},
+	}
+	for _, config := range configs {
+		token, _, err := NewSigner(config).Sign(7, "alice", "alice@example.com", TokenAccess, time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := verifier.Verify(token, TokenAccess); err != ErrInvalidToken {
+			t.Errorf("config %+v: expected ErrInvalidToken, got %v", config, err)
+		}
+	}
+}
+
+func TestVerify_Expired(t *testing.T) {
+	token, _, err := NewSigner(testConfig).Sign(7, "alice", "alice@example.com", TokenAccess, -time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := NewVerifier(testConfig).Verify(token, TokenAccess); err != ErrExpiredToken {
+		t.Fatalf("expected ErrExpiredToken, got %v", err)
+	}
+}
+
+func TestVerify_RejectsOtherAlgorithms(t *testing.T) {
+	claims := Claims{
+		UserID: 7,
+		Type:   TokenAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    DefaultIssuer,
+			Audience:  jwt.ClaimStrings{DefaultAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	if _, err := NewVerifier(testConfig).Verify(token, TokenAccess); err != ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestAuthenticate_RunsChecks(t *testing.T) {
+	token, _, err := NewSigner(testConfig).Sign(7, "alice", "alice@example.com", TokenAccess, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	revoked := errors.New("token revoked")
+	check := func(ctx context.Context, got string, claims *Claims) error {
+		if got != token || claims.UserID != 7 {
+			t.Errorf("check got token %q and claims %+v", got, claims)
+		}
+		return revoked
+	}
+
+	if _, err := NewVerifier(testConfig).Authenticate(context.Background(), token, check); err != revoked {
+		t.Fatalf("expected the check error, got %v", err)
+	}
+}
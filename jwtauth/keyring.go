@@ -0,0 +1,441 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Asymmetric signing algorithms a Key can use
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrNoSigningKey = errors.New("no active signing key")
+)
+
+// KeySet finds the public key a token was signed with by its kid header
+type KeySet interface {
+	PublicKey(kid string) (crypto.PublicKey, error)
+}
+
+// Key is a private signing key. It signs tokens from ActiveFrom until the
+// next key in its keyring becomes active.
+type Key struct {
+	ID         string
+	Private    crypto.Signer
+	ActiveFrom time.Time
+}
+
+// GenerateKey creates a key for alg (AlgRS256 or AlgEdDSA) with a random id
+func GenerateKey(alg string, activeFrom time.Time) (*Key, error) {
+	var private crypto.Signer
+	switch alg {
+	case AlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	case AlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &Key{ID: hex.EncodeToString(id), Private: private, ActiveFrom: activeFrom}, nil
+}
+
+// Algorithm is the JWT alg the key signs with
+func (k *Key) Algorithm() string {
+	if _, ok := k.Private.(*rsa.PrivateKey); ok {
+		return AlgRS256
+	}
+	return AlgEdDSA
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	if k.Algorithm() == AlgRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// RotationPolicy says how often a keyring rotates and how long keys are
+// published around the time they sign
+type RotationPolicy struct {
+	// Algorithm of the generated keys
+	Algorithm string
+	// Every is how long each key signs tokens
+	Every time.Duration
+	// Publish is how long a new key is served in the JWKS before it signs
+	// anything. It must cover how long verifiers cache the JWKS.
+	Publish time.Duration
+	// Retain is how long a key is kept for verification once it stopped
+	// signing. It must cover the longest-lived token.
+	Retain time.Duration
+}
+
+// Keyring holds the keys tokens are signed with. The newest active key
+// signs; every key in the ring verifies and is published in the JWKS,
+// including the next key before it becomes active.
+//
+// A keyring loaded from a directory stores each key there as <kid>.pem, so
+// the keys survive restarts and can be shared between replicas. Replicas
+// rotate one at a time and pick up each other's keys before signing or
+// verifying with them.
+type Keyring struct {
+	dir string
+
+	mu         sync.RWMutex
+	keys       []*Key
+	modTime    time.Time // of dir when it was last read
+	reloadedAt time.Time
+}
+
+// keyringReload is how often a keyring directory is read again even though
+// its modification time says nothing changed, for file systems with coarse
+// timestamps
+const keyringReload = time.Minute
+
+// NewKeyring returns an in-memory keyring holding keys
+func NewKeyring(keys ...*Key) *Keyring {
+	k := &Keyring{}
+	k.set(keys)
+	return k
+}
+
+// LoadKeyring reads the keys in dir, creating the directory if needed.
+// Keys added or rotated in later are written there too.
+func LoadKeyring(dir string) (*Keyring, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	k := &Keyring{dir: dir}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload re-reads the keyring directory, picking up keys rotated in by
+// other replicas. It does nothing for in-memory keyrings.
+func (k *Keyring) Reload() error {
+	if k.dir == "" {
+		return nil
+	}
+
+	// Taken before reading, so a change made while reading is read again
+	info, err := os.Stat(k.dir)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	paths, err := filepath.Glob(filepath.Join(k.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		key, err := readKeyFile(path)
+		if err != nil {
+			return fmt.Errorf("read key %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+
+	k.mu.Lock()
+	k.set(keys)
+	k.modTime = info.ModTime()
+	k.reloadedAt = now
+	k.mu.Unlock()
+	return nil
+}
+
+// refresh reloads the directory when keys were written to or removed from
+// it since it was last read, e.g. by another replica rotating. Failures
+// are logged and the keys already loaded are kept.
+func (k *Keyring) refresh() {
+	if k.dir == "" {
+		return
+	}
+	info, err := os.Stat(k.dir)
+	if err != nil {
+		log.Printf("Failed to check signing keys, using loaded keys: %v", err)
+		return
+	}
+
+	k.mu.RLock()
+	fresh := info.ModTime().Equal(k.modTime) && time.Since(k.reloadedAt) < keyringReload
+	k.mu.RUnlock()
+	if fresh {
+		return
+	}
+	if err := k.Reload(); err != nil {
+		log.Printf("Failed to reload signing keys, using loaded keys: %v", err)
+	}
+}
+
+func (k *Keyring) set(keys []*Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].ActiveFrom.Equal(keys[j].ActiveFrom) {
+			return keys[i].ActiveFrom.Before(keys[j].ActiveFrom)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	k.keys = keys
+}
+
+// Add puts key in the ring, writing it to the keyring directory if there
+// is one
+func (k *Keyring) Add(key *Key) error {
+	if k.dir != "" {
+		if err := writeKeyFile(filepath.Join(k.dir, key.ID+".pem"), key); err != nil {
+			return err
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.set(append(append([]*Key(nil), k.keys...), key))
+	return nil
+}
+
+// SigningKey is the newest key that is already active, among the keys
+// currently in the keyring directory
+func (k *Keyring) SigningKey() (*Key, error) {
+	k.refresh()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if !k.keys[i].ActiveFrom.After(now) {
+			return k.keys[i], nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// PublicKey returns the public half of the key with id kid. A kid the ring
+// does not hold is looked for in the keyring directory, where another
+// replica may just have put it.
+func (k *Keyring) PublicKey(kid string) (crypto.PublicKey, error) {
+	if key := k.find(kid); key != nil {
+		return key.Private.Public(), nil
+	}
+	k.refresh()
+	if key := k.find(kid); key != nil {
+		return key.Private.Public(), nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (k *Keyring) find(kid string) *Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.ID == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// JWKS is the public keys of the ring, pending ones included
+func (k *Keyring) JWKS() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, publicJWK(key.ID, key.Algorithm(), key.Private.Public()))
+	}
+	return set
+}
+
+// Rotate brings the ring in line with policy at now: it creates the first
+// key when the ring is empty, adds the next key policy.Publish before the
+// current one has signed for policy.Every, and drops keys that stopped
+// signing more than policy.Retain ago.
+//
+// Replicas sharing the keyring directory rotate one at a time, each seeing
+// the keys the one before it added, so only one of them adds the next key.
+func (k *Keyring) Rotate(policy RotationPolicy, now time.Time) error {
+	if k.dir != "" {
+		unlock, err := lockDir(k.dir)
+		if err != nil {
+			return fmt.Errorf("lock keyring: %w", err)
+		}
+		defer unlock()
+	}
+	if err := k.Reload(); err != nil {
+		return err
+	}
+
+	k.mu.RLock()
+	keys := append([]*Key(nil), k.keys...)
+	k.mu.RUnlock()
+
+	if len(keys) == 0 {
+		key, err := GenerateKey(policy.Algorithm, now)
+		if err != nil {
+			return err
+		}
+		return k.Add(key)
+	}
+
+	newest := keys[len(keys)-1]
+	if next := newest.ActiveFrom.Add(policy.Every); !now.Before(next.Add(-policy.Publish)) {
+		if earliest := now.Add(policy.Publish); next.Before(earliest) {
+			next = earliest
+		}
+		key, err := GenerateKey(policy.Algorithm, next)
+		if err != nil {
+			return err
+		}
+		if err := k.Add(key); err != nil {
+			return err
+		}
+	}
+
+	// A key stops signing once the key after it is active
+	for i, key := range keys[:len(keys)-1] {
+		if keys[i+1].ActiveFrom.Add(policy.Retain).Before(now) {
+			if err := k.remove(key.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RunRotation rotates the ring every interval until ctx is cancelled
+func (k *Keyring) RunRotation(ctx context.Context, policy RotationPolicy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := k.Rotate(policy, now); err != nil {
+				log.Printf("Failed to rotate signing keys: %v", err)
+			}
+		}
+	}
+}
+
+func (k *Keyring) remove(kid string) error {
+	if k.dir != "" {
+		if err := os.Remove(filepath.Join(k.dir, kid+".pem")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys := make([]*Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		if key.ID != kid {
+			keys = append(keys, key)
+		}
+	}
+	k.keys = keys
+	return nil
+}
+
+// activeFromHeader is the PEM header keeping a stored key's ActiveFrom.
+// Keys without it, e.g. made with openssl, are active right away.
+const activeFromHeader = "Active-From"
+
+func readKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Private = private
+	case ed25519.PrivateKey:
+		key.Private = private
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	if activeFrom, ok := block.Headers[activeFromHeader]; ok {
+		if key.ActiveFrom, err = time.Parse(time.RFC3339, activeFrom); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+func writeKeyFile(path string, key *Key) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return err
+	}
+	block := &pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{activeFromHeader: key.ActiveFrom.UTC().Format(time.RFC3339)},
+		Bytes:   der,
+	}
+
+	// Written aside and renamed, so replicas never read half a key
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, pem.EncodeToMemory(block), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
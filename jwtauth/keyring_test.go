@@ -0,0 +1,249 @@
+package jwtauth
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestKeyring(t *testing.T, alg string) *Keyring {
+	t.Helper()
+	key, err := GenerateKey(alg, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return NewKeyring(key)
+}
+
+func TestKeyring_SignAndVerify(t *testing.T) {
+	for _, alg := range []string{AlgEdDSA, AlgRS256} {
+		config := Config{Keys: newTestKeyring(t, alg)}
+
+		token, _, err := NewSigner(config).Sign(7, "alice", "alice@example.com", TokenAccess, time.Hour)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", alg, err)
+		}
+		claims, err := NewVerifier(config).Verify(token, TokenAccess)
+		if err != nil {
+			t.Fatalf("%s: unexpected validation error: %v", alg, err)
+		}
+		if claims.UserID != 7 {
+			t.Errorf("%s: claims mismatch: got %+v", alg, claims)
+		}
+	}
+}
+
+func TestKeyring_RejectsOtherKeysAndSecrets(t *testing.T) {
+	verifier := NewVerifier(Config{Keys: newTestKeyring(t, AlgEdDSA)})
+
+	// Signed by a key the ring does not hold
+	foreign, _, err := NewSigner(Config{Keys: newTestKeyring(t, AlgEdDSA)}).Sign(7, "alice", "", TokenAccess, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := verifier.Verify(foreign, TokenAccess); err != ErrInvalidToken {
+		t.Errorf("foreign key: expected ErrInvalidToken, got %v", err)
+	}
+
+	// HS256 tokens are not accepted once keys are configured
+	shared, _, err := NewSigner(testConfig).Sign(7, "alice", "", TokenAccess, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := verifier.Verify(shared, TokenAccess); err != ErrInvalidToken {
+		t.Errorf("HS256: expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestKeyring_AcceptsHS256DuringTransition(t *testing.T) {
+	keys := newTestKeyring(t, AlgEdDSA)
+	shared, _, err := NewSigner(testConfig).Sign(7, "alice", "", TokenRefresh, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	transition := Config{Secret: testConfig.Secret, Keys: keys, AcceptHS256Until: time.Now().Add(time.Hour)}
+	if _, err := NewVerifier(transition).Verify(shared, TokenRefresh); err != nil {
+		t.Errorf("expected HS256 to be accepted until the cutoff, got %v", err)
+	}
+
+	// Tokens signed with the keys still verify next to the secret
+	signed, _, err := NewSigner(Config{Keys: keys}).Sign(7, "alice", "", TokenAccess, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := NewVerifier(transition).Verify(signed, TokenAccess); err != nil {
+		t.Errorf("expected the keyring token to verify, got %v", err)
+	}
+
+	transition.AcceptHS256Until = time.Now().Add(-time.Second)
+	if _, err := NewVerifier(transition).Verify(shared, TokenRefresh); err != ErrInvalidToken {
+		t.Errorf("after the cutoff: expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	keyring, err := LoadKeyring(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to load keyring: %v", err)
+	}
+	policy := RotationPolicy{
+		Algorithm: AlgEdDSA,
+		Every:     24 * time.Hour,
+		Publish:   time.Hour,
+		Retain:    2 * time.Hour,
+	}
+	start := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+
+	// An empty ring gets a key that signs right away
+	if err := keyring.Rotate(policy, start); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	first := keyring.JWKS().Keys
+	if len(first) != 1 {
+		t.Fatalf("expected one key, got %d", len(first))
+	}
+
+	// An hour before the day is up the next key is published, not yet signing
+	if err := keyring.Rotate(policy, start.Add(23*time.Hour)); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if got := len(keyring.JWKS().Keys); got != 2 {
+		t.Fatalf("expected the next key to be published, got %d keys", got)
+	}
+
+	// Keys survive a reload from the directory, activation times included
+	reloaded, err := LoadKeyring(keyring.dir)
+	if err != nil {
+		t.Fatalf("failed to reload keyring: %v", err)
+	}
+	signing, err := reloaded.SigningKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if signing.ID == first[0].KeyID {
+		t.Errorf("expected the second key to sign by now")
+	}
+
+	// Once retained long enough after its successor took over, the first
+	// key is dropped
+	if err := keyring.Rotate(policy, start.Add(27*time.Hour)); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if _, err := keyring.PublicKey(first[0].KeyID); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected the first key to be dropped, got %v", err)
+	}
+	if _, err := keyring.PublicKey(signing.ID); err != nil {
+		t.Errorf("expected the signing key to be kept, got %v", err)
+	}
+}
+
+func TestKeyring_ReplicasRotateOnce(t *testing.T) {
+	dir := t.TempDir()
+	policy := RotationPolicy{Algorithm: AlgEdDSA, Every: 24 * time.Hour, Publish: time.Hour, Retain: 72 * time.Hour}
+	start := time.Now().Add(-48 * time.Hour)
+
+	replicas := make([]*Keyring, 8)
+	for i := range replicas {
+		keyring, err := LoadKeyring(dir)
+		if err != nil {
+			t.Fatalf("failed to load keyring: %v", err)
+		}
+		replicas[i] = keyring
+	}
+
+	// All replicas find the ring empty, then the next key due, at once
+	for round, now := range []time.Time{start, start.Add(23 * time.Hour)} {
+		var wg sync.WaitGroup
+		for _, keyring := range replicas {
+			wg.Add(1)
+			go func(keyring *Keyring) {
+				defer wg.Done()
+				if err := keyring.Rotate(policy, now); err != nil {
+					t.Errorf("rotate: %v", err)
+				}
+			}(keyring)
+		}
+		wg.Wait()
+
+		paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			t.Fatalf("glob: %v", err)
+		}
+		if len(paths) != round+1 {
+			t.Fatalf("round %d: expected %d keys, got %d", round, round+1, len(paths))
+		}
+	}
+}
+
+func TestKeyring_RotateWaitsForOtherReplicas(t *testing.T) {
+	keyring, err := LoadKeyring(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to load keyring: %v", err)
+	}
+	policy := RotationPolicy{Algorithm: AlgEdDSA, Every: 24 * time.Hour, Publish: time.Hour, Retain: time.Hour}
+
+	// Another replica is rotating
+	unlock, err := lockDir(keyring.dir)
+	if err != nil {
+		t.Fatalf("failed to lock: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- keyring.Rotate(policy, time.Now()) }()
+
+	select {
+	case <-done:
+		t.Fatal("expected Rotate to wait for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+}
+
+func TestKeyring_PicksUpKeysOfOtherReplicas(t *testing.T) {
+	dir := t.TempDir()
+	verifying, err := LoadKeyring(dir)
+	if err != nil {
+		t.Fatalf("failed to load keyring: %v", err)
+	}
+	signing, err := LoadKeyring(dir)
+	if err != nil {
+		t.Fatalf("failed to load keyring: %v", err)
+	}
+
+	// Rotated in by the signing replica only
+	key, err := GenerateKey(AlgEdDSA, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := signing.Add(key); err != nil {
+		t.Fatalf("failed to add key: %v", err)
+	}
+
+	token, _, err := NewSigner(Config{Keys: signing}).Sign(7, "alice", "", TokenAccess, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := NewVerifier(Config{Keys: verifying}).Verify(token, TokenAccess); err != nil {
+		t.Fatalf("expected the other replica's key to be picked up, got %v", err)
+	}
+	if current, err := verifying.SigningKey(); err != nil || current.ID != key.ID {
+		t.Errorf("expected to sign with the other replica's key, got %v, %v", current, err)
+	}
+}
+
+func TestKeyring_NoActiveKey(t *testing.T) {
+	key, err := GenerateKey(AlgEdDSA, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	_, _, err = NewSigner(Config{Keys: NewKeyring(key)}).Sign(7, "alice", "", TokenAccess, time.Hour)
+	if !errors.Is(err, ErrNoSigningKey) {
+		t.Fatalf("expected ErrNoSigningKey, got %v", err)
+	}
+}
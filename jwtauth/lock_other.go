@@ -0,0 +1,15 @@
+//go:build !unix
+
+package jwtauth
+
+import "sync"
+
+// dirLocks serializes rotations within the process where file locks are not
+// available; replicas sharing a keyring directory need a unix system
+var dirLocks sync.Map
+
+func lockDir(dir string) (unlock func(), err error) {
+	mu, _ := dirLocks.LoadOrStore(dir, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock, nil
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package jwtauth
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// rotateLockFile is locked in a keyring directory while a replica rotates
+const rotateLockFile = ".rotate.lock"
+
+// lockDir takes an exclusive lock on dir, waiting for whoever holds it.
+// The lock goes away with the process, so a crashed replica never keeps
+// the others from rotating.
+func lockDir(dir string) (unlock func(), err error) {
+	f, err := os.OpenFile(filepath.Join(dir, rotateLockFile), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,46 @@
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RevokedChannel is the Redis channel revoked access tokens are announced
+// on, so open connections can be closed right away
+const RevokedChannel = "auth:revoked"
+
+var (
+	ErrTokenRevoked          = errors.New("token revoked")
+	ErrRevocationUnavailable = fmt.Errorf("token revocation list is unavailable: %w", ErrUnavailable)
+)
+
+// RevokedKey is the Redis key that marks token as revoked until it expires
+func RevokedKey(token string) string {
+	return "revoked:" + token
+}
+
+// NotRevoked checks tokens against the RevokedKey keys written on logout.
+// It returns ErrTokenRevoked for revoked tokens. When Redis cannot be
+// reached, failOpen lets tokens through, otherwise they are rejected with
+// ErrRevocationUnavailable.
+func NotRevoked(client *redis.Client, failOpen bool) Check {
+	return func(ctx context.Context, token string, _ *Claims) error {
+		exists, err := client.Exists(ctx, RevokedKey(token)).Result()
+		if err != nil {
+			if failOpen {
+				log.Printf("Revocation check failed, letting token through: %v", err)
+				return nil
+			}
+			log.Printf("Revocation check failed, rejecting token: %v", err)
+			return ErrRevocationUnavailable
+		}
+		if exists > 0 {
+			return ErrTokenRevoked
+		}
+		return nil
+	}
+}
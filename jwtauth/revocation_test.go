@@ -0,0 +1,52 @@
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRevocationCheck(t *testing.T, failOpen bool) (Check, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return NotRevoked(client, failOpen), server
+}
+
+func TestNotRevoked(t *testing.T) {
+	check, server := newTestRevocationCheck(t, false)
+	if err := server.Set(RevokedKey("old-token"), "1"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := check(ctx, "old-token", nil); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected ErrTokenRevoked, got %v", err)
+	}
+	if err := check(ctx, "fresh-token", nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNotRevoked_RedisDown(t *testing.T) {
+	ctx := context.Background()
+
+	open, server := newTestRevocationCheck(t, true)
+	server.Close()
+	if err := open(ctx, "token", nil); err != nil {
+		t.Errorf("expected fail-open to let tokens through, got %v", err)
+	}
+
+	closed, server := newTestRevocationCheck(t, false)
+	server.Close()
+	err := closed(ctx, "token", nil)
+	if !errors.Is(err, ErrRevocationUnavailable) {
+		t.Errorf("expected ErrRevocationUnavailable, got %v", err)
+	}
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected fail-closed errors to wrap ErrUnavailable, got %v", err)
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 
+	"github.com/zhanserikAmangeldi/jwtauth"
+	userpb "github.com/zhanserikAmangeldi/proto/user"
 	"github.com/zhanserikAmangeldi/user-service/internal/config"
+	usergrpc "github.com/zhanserikAmangeldi/user-service/internal/grpc"
 	"github.com/zhanserikAmangeldi/user-service/internal/handler"
 	"github.com/zhanserikAmangeldi/user-service/internal/mailer"
 	"github.com/zhanserikAmangeldi/user-service/internal/middleware"
@@ -20,6 +26,15 @@ import (
 	"github.com/zhanserikAmangeldi/user-service/pkg/jwt"
 )
 
+const (
+	// keyRotationCheck is how often the keyring checks whether a key is
+	// due, and picks up keys rotated in by other replicas
+	keyRotationCheck = time.Minute
+	// jwksMaxAge is how long verifiers may cache the JWKS; keys are
+	// published JWT_KEY_PUBLISH ahead of signing, which must be longer
+	jwksMaxAge = 5 * time.Minute
+)
+
 func main() {
 	cfg := config.LoadConfig()
 	ctx := context.Background()
@@ -71,9 +86,47 @@ func main() {
 	sessionRepo := repository.NewSessionRepository(dbPool)
 	emailRepo := repository.NewEmailVerificationRepository(dbPool)
 
-	tokenManager := jwt.NewTokenManager(cfg.JWTSecret)
+	jwtConfig := jwtauth.Config{
+		Secret:           cfg.JWTSecret,
+		Issuer:           cfg.JWTIssuer,
+		Audience:         cfg.JWTAudience,
+		AcceptHS256Until: cfg.JWTAcceptHS256Until,
+	}
+
+	// Tokens are signed with rotating private keys whose public halves are
+	// served as a JWKS, so verifiers need no secret. HS256 keeps the
+	// shared secret.
+	var keyring *jwtauth.Keyring
+	if cfg.JWTSigningAlg != "HS256" {
+		keyring, err = jwtauth.LoadKeyring(cfg.JWTKeysDir)
+		if err != nil {
+			log.Fatalf("Failed to load signing keys: %v", err)
+		}
+		policy := jwtauth.RotationPolicy{
+			Algorithm: cfg.JWTSigningAlg,
+			Every:     cfg.JWTKeyRotation,
+			Publish:   cfg.JWTKeyPublish,
+			Retain:    cfg.JWTKeyRetain,
+		}
+		if err := keyring.Rotate(policy, time.Now()); err != nil {
+			log.Fatalf("Failed to rotate signing keys: %v", err)
+		}
+		go keyring.RunRotation(ctx, policy, keyRotationCheck)
+		jwtConfig.Keys = keyring
+		log.Printf("Signing tokens with %s keys from %s, rotated every %s", cfg.JWTSigningAlg, cfg.JWTKeysDir, cfg.JWTKeyRotation)
+	}
+	tokenManager := jwt.NewTokenManagerWithConfig(jwtConfig)
 	authService := service.NewAuthService(userRepo, sessionRepo, tokenManager, emailRepo, &smtp, redisClient)
 
+	// Status and last seen follow the WebSocket connections held by
+	// chat-service
+	presenceService := service.NewPresenceService(userRepo, redisClient)
+	go func() {
+		if err := presenceService.Run(ctx); err != nil {
+			log.Printf("Presence consumer stopped: %v", err)
+		}
+	}()
+
 	authHandler := handler.NewAuthHandler(authService)
 	userHandler := handler.NewUserHandler(userRepo)
 	emailVerificationHandler := handler.NewEmailVerificationHandler(authService)
@@ -105,6 +158,10 @@ func main() {
 
 	router.GET("/verify-email", emailVerificationHandler.VerifyEmail)
 
+	if keyring != nil {
+		router.GET(jwtauth.JWKSPath, gin.WrapH(jwtauth.JWKSHandler(keyring, jwksMaxAge)))
+	}
+
 	v1 := router.Group("/api/v1")
 	{
 		auth := v1.Group("/auth")
@@ -116,8 +173,11 @@ func main() {
 		}
 	}
 
+	// Logged-out tokens are rejected over HTTP and gRPC alike
+	notRevoked := jwtauth.NotRevoked(redisClient, cfg.RevocationFail != "closed")
+
 	protected := v1.Group("")
-	protected.Use(middleware.AuthMiddleware(tokenManager, redisClient))
+	protected.Use(middleware.AuthMiddleware(tokenManager, notRevoked))
 	{
 		auth := protected.Group("/auth")
 		{
@@ -133,11 +193,31 @@ func main() {
 		}
 	}
 
+	// gRPC API for the other services
+	if cfg.GRPCServiceToken == "" {
+		log.Fatal("USER_SERVICE_GRPC_TOKEN must be set, the gRPC API is not open to end users")
+	}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(usergrpc.RequireServiceToken(cfg.GRPCServiceToken)))
+	userpb.RegisterUserServiceServer(grpcServer, usergrpc.NewUserServer(userRepo, tokenManager, notRevoked))
+
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		log.Fatalf("Failed to listen on gRPC port: %v", err)
+	}
+	go func() {
+		log.Printf("User gRPC service starting on port %s", cfg.GRPCPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("gRPC server failed: %v", err)
+		}
+	}()
+	defer grpcServer.GracefulStop()
+
 	srv := &http.Server{
 		Addr:    ":" + cfg.HTTPPort,
 		Handler: router,
 	}
 
+	log.Printf("Token revocation: fail %s", cfg.RevocationFail)
 	log.Printf("User service starting on port %s", cfg.HTTPPort)
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Failed to start server: %v", err)
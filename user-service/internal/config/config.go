@@ -1,32 +1,71 @@
 package config
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 type Config struct {
-	HTTPPort   string
-	GRPCPort   string
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	RedisHost  string
-	RedisPort  string
-	JWTSecret  string
+	HTTPPort    string
+	GRPCPort    string
+	DBHost      string
+	DBPort      string
+	DBUser      string
+	DBPassword  string
+	DBName      string
+	RedisHost   string
+	RedisPort   string
+	JWTSecret   string
+	JWTIssuer   string
+	JWTAudience string
+
+	// GRPCServiceToken is the bearer token other services must present on
+	// every gRPC call
+	GRPCServiceToken string
+
+	// JWTSigningAlg is RS256 or EdDSA for keys from JWTKeysDir, rotated by
+	// the JWTKey* durations, or HS256 for the shared JWTSecret
+	JWTSigningAlg  string
+	JWTKeysDir     string
+	JWTKeyRotation time.Duration
+	JWTKeyPublish  time.Duration
+	JWTKeyRetain   time.Duration
+	// JWTAcceptHS256Until keeps HS256 tokens signed with JWTSecret valid
+	// next to the keys until then, see jwtauth.Config
+	JWTAcceptHS256Until time.Time
+
+	// RevocationFail is open to accept tokens while Redis is down, or
+	// closed to reject them
+	RevocationFail string
 }
 
 func LoadConfig() *Config {
 	return &Config{
-		HTTPPort:   getEnv("HTTP_PORT", "8081"),
-		GRPCPort:   getEnv("GRPC_PORT", "9091"),
-		DBHost:     getEnv("USER_DB_HOST", "localhost"),
-		DBPort:     getEnv("USER_DB_PORT", "5432"),
-		DBUser:     getEnv("USER_DB_USER", "chatuser"),
-		DBPassword: getEnv("USER_DB_PASSWORD", "chatpass123"),
-		DBName:     getEnv("USER_DB_NAME", "chatapp"),
-		RedisHost:  getEnv("REDIS_HOST", "localhost"),
-		RedisPort:  getEnv("REDIS_PORT", "6379"),
-		JWTSecret:  getEnv("JWT_SECRET", "your-super-secret-key"),
+		HTTPPort:    getEnv("HTTP_PORT", "8081"),
+		GRPCPort:    getEnv("GRPC_PORT", "9091"),
+		DBHost:      getEnv("USER_DB_HOST", "localhost"),
+		DBPort:      getEnv("USER_DB_PORT", "5432"),
+		DBUser:      getEnv("USER_DB_USER", "chatuser"),
+		DBPassword:  getEnv("USER_DB_PASSWORD", "chatpass123"),
+		DBName:      getEnv("USER_DB_NAME", "chatapp"),
+		RedisHost:   getEnv("REDIS_HOST", "localhost"),
+		RedisPort:   getEnv("REDIS_PORT", "6379"),
+		JWTSecret:   getEnv("JWT_SECRET", "your-super-secret-key"),
+		JWTIssuer:   getEnv("JWT_ISSUER", "user-service"),
+		JWTAudience: getEnv("JWT_AUDIENCE", "chat-app"),
+
+		GRPCServiceToken: getEnv("USER_SERVICE_GRPC_TOKEN", ""),
+
+		JWTSigningAlg:  getEnv("JWT_SIGNING_ALG", "EdDSA"),
+		JWTKeysDir:     getEnv("JWT_KEYS_DIR", "keys"),
+		JWTKeyRotation: getDuration("JWT_KEY_ROTATION", 30*24*time.Hour),
+		JWTKeyPublish:  getDuration("JWT_KEY_PUBLISH", time.Hour),
+		// Long enough for the last refresh token signed with a key
+		JWTKeyRetain: getDuration("JWT_KEY_RETAIN", 7*24*time.Hour),
+		// Past the refresh-token lifetime after switching from HS256
+		JWTAcceptHS256Until: getTime("JWT_ACCEPT_HS256_UNTIL"),
+
+		RevocationFail: getEnv("REVOCATION_FAIL_MODE", "open"),
 	}
 }
 
@@ -36,3 +75,22 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getTime reads an RFC 3339 timestamp, the zero time if the variable is
+// unset or malformed
+func getTime(key string) time.Time {
+	value, err := time.Parse(time.RFC3339, os.Getenv(key))
+	if err != nil {
+		return time.Time{}
+	}
+	return value
+}
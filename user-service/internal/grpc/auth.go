@@ -0,0 +1,27 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequireServiceToken rejects calls that do not carry token as a bearer
+// token in their authorization metadata. The token is shared with the
+// services allowed to call the user service; end users never get it.
+func RequireServiceToken(token string) grpc.UnaryServerInterceptor {
+	want := []byte("Bearer " + token)
+
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), want) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "missing or invalid service token")
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/zhanserikAmangeldi/jwtauth"
+	userpb "github.com/zhanserikAmangeldi/proto/user"
+	"github.com/zhanserikAmangeldi/user-service/internal/models"
+	"github.com/zhanserikAmangeldi/user-service/internal/repository"
+	"github.com/zhanserikAmangeldi/user-service/pkg/jwt"
+)
+
+// maxBatchSize caps the users looked up in one BatchGetUsers call
+const maxBatchSize = 500
+
+// UserServer serves user lookups and token checks to the other services
+type UserServer struct {
+	userpb.UnimplementedUserServiceServer
+
+	userRepo     *repository.UserRepository
+	tokenManager *jwt.TokenManager
+	notRevoked   jwtauth.Check
+}
+
+func NewUserServer(userRepo *repository.UserRepository, tokenManager *jwt.TokenManager, notRevoked jwtauth.Check) *UserServer {
+	return &UserServer{
+		userRepo:     userRepo,
+		tokenManager: tokenManager,
+		notRevoked:   notRevoked,
+	}
+}
+
+func (s *UserServer) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, req.GetUserId())
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user %d not found", req.GetUserId())
+		}
+		return nil, status.Error(codes.Internal, "failed to load user")
+	}
+
+	return &userpb.GetUserResponse{User: toProto(user)}, nil
+}
+
+func (s *UserServer) BatchGetUsers(ctx context.Context, req *userpb.BatchGetUsersRequest) (*userpb.BatchGetUsersResponse, error) {
+	if len(req.GetUserIds()) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d users can be requested at once", maxBatchSize)
+	}
+	if len(req.GetUserIds()) == 0 {
+		return &userpb.BatchGetUsersResponse{}, nil
+	}
+
+	users, err := s.userRepo.GetByIDs(ctx, req.GetUserIds())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to load users")
+	}
+
+	resp := &userpb.BatchGetUsersResponse{Users: make([]*userpb.User, 0, len(users))}
+	for _, user := range users {
+		resp.Users = append(resp.Users, toProto(user))
+	}
+	return resp, nil
+}
+
+// ValidateToken answers invalid, expired and revoked tokens with valid set
+// to false rather than an error. When revocations cannot be checked and the
+// check fails closed, it answers Unavailable.
+func (s *UserServer) ValidateToken(ctx context.Context, req *userpb.ValidateTokenRequest) (*userpb.ValidateTokenResponse, error) {
+	token := req.GetToken()
+	if token == "" {
+		return &userpb.ValidateTokenResponse{}, nil
+	}
+
+	if err := s.notRevoked(ctx, token, nil); err != nil {
+		if errors.Is(err, jwtauth.ErrUnavailable) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return &userpb.ValidateTokenResponse{}, nil
+	}
+
+	claims, err := s.tokenManager.ValidateAccessToken(token)
+	if err != nil {
+		return &userpb.ValidateTokenResponse{}, nil
+	}
+
+	resp := &userpb.ValidateTokenResponse{
+		Valid:    true,
+		UserId:   claims.UserID,
+		Username: claims.Username,
+		Email:    claims.Email,
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = timestamppb.New(claims.ExpiresAt.Time)
+	}
+	return resp, nil
+}
+
+func (s *UserServer) CheckUserExists(ctx context.Context, req *userpb.CheckUserExistsRequest) (*userpb.CheckUserExistsResponse, error) {
+	exists, err := s.userRepo.Exists(ctx, req.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to look user up")
+	}
+
+	return &userpb.CheckUserExistsResponse{Exists: exists}, nil
+}
+
+func toProto(user *models.User) *userpb.User {
+	pb := &userpb.User{
+		Id:       user.ID,
+		Username: user.Username,
+		Status:   user.Status,
+	}
+	if user.DisplayName != nil {
+		pb.DisplayName = *user.DisplayName
+	}
+	if user.AvatarURL != nil {
+		pb.AvatarUrl = *user.AvatarURL
+	}
+	if user.LastSeenAt != nil {
+		pb.LastSeenAt = timestamppb.New(*user.LastSeenAt)
+	}
+	return pb
+}
@@ -1,84 +1,39 @@
 package middleware
 
 import (
-	"github.com/redis/go-redis/v9"
-	"github.com/zhanserikAmangeldi/user-service/pkg/jwt"
-	"net/http"
-	"strings"
-
 	"github.com/gin-gonic/gin"
-)
 
-const (
-	authorizationHeader = "Authorization"
-	userIDKey           = "user_id"
-	usernameKey         = "username"
-	emailKey            = "email"
+	"github.com/zhanserikAmangeldi/jwtauth"
+	"github.com/zhanserikAmangeldi/jwtauth/ginauth"
+	"github.com/zhanserikAmangeldi/user-service/pkg/jwt"
 )
 
-func AuthMiddleware(tokenManager *jwt.TokenManager, redisClient *redis.Client) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader(authorizationHeader)
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
-			c.Abort()
-			return
-		}
-
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
-			c.Abort()
-			return
-		}
-
-		token := parts[1]
-
-		ctx := c.Request.Context()
-
-		exists, err := redisClient.Exists(ctx, "revoked:"+token).Result()
-		if err == nil && exists > 0 {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
-			c.Abort()
-			return
-		}
-
-		claims, err := tokenManager.ValidateToken(token)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
-			c.Abort()
-			return
-		}
-
-		c.Set(userIDKey, claims.UserId)
-		c.Set(usernameKey, claims.Username)
-		c.Set(emailKey, claims.Email)
-
-		c.Next()
-	}
+// AuthMiddleware accepts valid access tokens that pass notRevoked
+func AuthMiddleware(tokenManager *jwt.TokenManager, notRevoked jwtauth.Check) gin.HandlerFunc {
+	return ginauth.Middleware(tokenManager.Verifier(), notRevoked)
 }
 
 // Хелперы для получения данных из контекста
 func GetUserID(c *gin.Context) int64 {
-	userID, exists := c.Get(userIDKey)
-	if !exists {
+	claims, ok := ginauth.Claims(c)
+	if !ok {
 		return 0
 	}
-	return userID.(int64)
+	return claims.UserID
 }
 
 func GetUsername(c *gin.Context) string {
-	username, exists := c.Get(usernameKey)
-	if !exists {
+	claims, ok := ginauth.Claims(c)
+	if !ok {
 		return ""
 	}
-	return username.(string)
+	return claims.Username
 }
 
 func GetEmail(c *gin.Context) string {
-	email, exists := c.Get(emailKey)
-	if !exists {
+	claims, ok := ginauth.Claims(c)
+	if !ok {
 		return ""
 	}
-	return email.(string)
+	return claims.Email
 }
@@ -79,6 +79,54 @@ func (r *UserRepository) GetByID(ctx context.Context, id int64) (*models.User, e
 	return user, nil
 }
 
+// GetByIDs returns the users among ids that exist, in no particular order
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []int64) ([]*models.User, error) {
+	query := `
+		SELECT id, username, email, password_hash, display_name, avatar_url,
+		       bio, status, last_seen_at, created_at, updated_at
+		FROM users
+		WHERE id = ANY($1) AND deleted_at IS NULL
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*models.User, 0, len(ids))
+	for rows.Next() {
+		user := &models.User{}
+		if err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.PasswordHash,
+			&user.DisplayName,
+			&user.AvatarURL,
+			&user.Bio,
+			&user.Status,
+			&user.LastSeenAt,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// Exists reports whether a user that is not deleted has the given id
+func (r *UserRepository) Exists(ctx context.Context, id int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`
+
+	var exists bool
+	err := r.db.QueryRow(ctx, query, id).Scan(&exists)
+	return exists, err
+}
+
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
 		SELECT id, username, email, password_hash, display_name, avatar_url,
@@ -182,6 +230,21 @@ func (r *UserRepository) UpdateLastSeen(ctx context.Context, userID int64) error
 	return err
 }
 
+// UpdatePresence records a status reported by chat-service. A busy status
+// set by the user is kept until they go offline; lastSeenAt only moves
+// forward.
+func (r *UserRepository) UpdatePresence(ctx context.Context, userID int64, status string, lastSeenAt *time.Time) error {
+	query := `
+		UPDATE users
+		SET status = CASE WHEN status = 'busy' AND $2 <> 'offline' THEN status ELSE $2 END,
+		    last_seen_at = GREATEST(last_seen_at, $3)
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	_, err := r.db.Exec(ctx, query, userID, status, lastSeenAt)
+	return err
+}
+
 func (r *UserRepository) MarkVerified(ctx context.Context, userID int64) error {
 	query := `
 		UPDATE users
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"github.com/zhanserikAmangeldi/jwtauth"
 	"github.com/zhanserikAmangeldi/user-service/internal/dto"
 	"github.com/zhanserikAmangeldi/user-service/internal/models"
 	"github.com/zhanserikAmangeldi/user-service/internal/repository"
@@ -194,12 +195,12 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, use
 		return nil, err
 	}
 
-	claims, err := s.tokenManager.ValidateToken(refreshToken)
+	claims, err := s.tokenManager.ValidateRefreshToken(refreshToken)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.userRepo.GetByID(ctx, claims.UserId)
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -240,14 +241,15 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, use
 }
 
 func (s *AuthService) Logout(ctx context.Context, refreshToken, accessToken string) error {
-	claims, err := s.tokenManager.ValidateToken(accessToken)
+	claims, err := s.tokenManager.ValidateAccessToken(accessToken)
 	if err == nil {
 		ttl := time.Until(claims.ExpiresAt.Time)
 		if ttl > 0 {
-			key := fmt.Sprintf("revoked:%s", accessToken)
+			key := jwtauth.RevokedKey(accessToken)
 			_ = s.redisClient.Set(ctx, key, "revoked", ttl).Err()
-			log.Printf("[INFO] Tokens blacklisted for userID=%s (accessToken=%s..., refreshToken=%s...)",
-				claims.UserId, accessToken[:10], refreshToken[:10])
+			_ = s.redisClient.Publish(ctx, jwtauth.RevokedChannel, accessToken).Err()
+			log.Printf("[INFO] Tokens blacklisted for userID=%d (accessToken=%s..., refreshToken=%s...)",
+				claims.UserID, accessToken[:10], refreshToken[:10])
 		}
 	} else {
 		return err
@@ -269,12 +271,13 @@ func (s *AuthService) LogoutAll(ctx context.Context, userID int64) error {
 			continue
 		}
 
-		claims, err := s.tokenManager.ValidateToken(accessToken)
+		claims, err := s.tokenManager.ValidateAccessToken(accessToken)
 		if err == nil {
 			ttl := time.Until(claims.ExpiresAt.Time)
 			if ttl > 0 {
-				key := fmt.Sprintf("revoked:%s", accessToken)
+				key := jwtauth.RevokedKey(accessToken)
 				_ = s.redisClient.Set(ctx, key, "revoked", ttl).Err()
+				_ = s.redisClient.Publish(ctx, jwtauth.RevokedChannel, accessToken).Err()
 			}
 		}
 	}
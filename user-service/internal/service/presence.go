@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhanserikAmangeldi/user-service/internal/repository"
+)
+
+// PresenceStream is the Redis stream chat-service appends presence
+// changes to
+const PresenceStream = "presence:changes"
+
+const (
+	// presenceGroup is the consumer group every user-service replica reads
+	// PresenceStream in, so each change is persisted by one of them
+	presenceGroup = "user-service"
+	// presenceBatch is how many changes are read at once
+	presenceBatch = 100
+	// presenceRetry is how long a change that failed to persist, or was
+	// read by a replica that died, waits before it is tried again
+	presenceRetry = 30 * time.Second
+)
+
+// presenceChange is a status change as published by chat-service
+type presenceChange struct {
+	UserID     int64      `json:"user_id"`
+	Status     string     `json:"status"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+}
+
+// PresenceService keeps users.status and users.last_seen_at in sync with
+// the live connections tracked by chat-service
+type PresenceService struct {
+	userRepo    *repository.UserRepository
+	redisClient *redis.Client
+	consumer    string
+}
+
+func NewPresenceService(userRepo *repository.UserRepository, redisClient *redis.Client) *PresenceService {
+	consumer, err := os.Hostname()
+	if err != nil {
+		consumer = "user-service"
+	}
+	return &PresenceService{
+		userRepo:    userRepo,
+		redisClient: redisClient,
+		consumer:    consumer,
+	}
+}
+
+// Run persists presence changes until ctx is cancelled. A change is only
+// acknowledged once saved; changes that failed, and changes left behind by
+// a replica that stopped, are claimed again after presenceRetry.
+func (s *PresenceService) Run(ctx context.Context) error {
+	err := s.redisClient.XGroupCreateMkStream(ctx, PresenceStream, presenceGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	ticker := time.NewTicker(presenceRetry)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.retryPending(ctx)
+		default:
+		}
+
+		streams, err := s.redisClient.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    presenceGroup,
+			Consumer: s.consumer,
+			Streams:  []string{PresenceStream, ">"},
+			Count:    presenceBatch,
+			Block:    5 * time.Second,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("Failed to read presence changes: %v", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, stream := range streams {
+			s.persist(ctx, stream.Messages)
+		}
+	}
+}
+
+// retryPending claims the changes that have been pending for presenceRetry,
+// this replica's failed ones included, and persists them
+func (s *PresenceService) retryPending(ctx context.Context) {
+	start := "0-0"
+	for {
+		messages, next, err := s.redisClient.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   PresenceStream,
+			Group:    presenceGroup,
+			Consumer: s.consumer,
+			MinIdle:  presenceRetry,
+			Start:    start,
+			Count:    presenceBatch,
+		}).Result()
+		if err != nil {
+			log.Printf("Failed to claim pending presence changes: %v", err)
+			return
+		}
+		s.persist(ctx, messages)
+		if next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+// persist saves changes and acknowledges the ones that were saved or can
+// never be
+func (s *PresenceService) persist(ctx context.Context, messages []redis.XMessage) {
+	for _, msg := range messages {
+		var change presenceChange
+		data, _ := msg.Values["presence"].(string)
+		if err := json.Unmarshal([]byte(data), &change); err != nil {
+			log.Printf("Dropping malformed presence change %s: %v", msg.ID, err)
+		} else if err := s.userRepo.UpdatePresence(ctx, change.UserID, change.Status, change.LastSeenAt); err != nil {
+			log.Printf("Failed to save presence of user %d, will retry: %v", change.UserID, err)
+			continue
+		}
+
+		if err := s.redisClient.XAck(ctx, PresenceStream, presenceGroup, msg.ID).Err(); err != nil {
+			log.Printf("Failed to acknowledge presence change %s: %v", msg.ID, err)
+		}
+	}
+}
@@ -1,96 +1,65 @@
 package jwt
 
 import (
-	"errors"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhanserikAmangeldi/jwtauth"
 )
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
-	ErrExpiredToken = errors.New("expired token")
+	ErrInvalidToken = jwtauth.ErrInvalidToken
+	ErrExpiredToken = jwtauth.ErrExpiredToken
 )
 
-type Claims struct {
-	UserId   int64  `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	jwt.RegisteredClaims
-}
+// Claims are the shared token claims, see jwtauth.Claims
+type Claims = jwtauth.Claims
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 7 * 24 * time.Hour
+)
 
+// TokenManager issues the user service's access and refresh tokens
 type TokenManager struct {
-	secretKey string
+	signer   *jwtauth.Signer
+	verifier *jwtauth.Verifier
 }
 
 func NewTokenManager(secretKey string) *TokenManager {
-	return &TokenManager{secretKey: secretKey}
+	return NewTokenManagerWithConfig(jwtauth.Config{Secret: secretKey})
 }
 
-func (tm *TokenManager) GenerateAccessToken(userId int64, username, email string) (string, time.Time, error) {
-	expiresAt := time.Now().Add(time.Minute * 15)
-
-	claims := Claims{
-		UserId:   userId,
-		Username: username,
-		Email:    email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+// NewTokenManagerWithConfig also sets the issuer and audience tokens are
+// issued for, and the keyring signing them in place of the secret
+func NewTokenManagerWithConfig(config jwtauth.Config) *TokenManager {
+	return &TokenManager{
+		signer:   jwtauth.NewSigner(config),
+		verifier: jwtauth.NewVerifier(config),
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(tm.secretKey))
-	if err != nil {
-		return "", time.Time{}, err
-	}
+// Verifier checks tokens issued by this manager
+func (tm *TokenManager) Verifier() *jwtauth.Verifier {
+	return tm.verifier
+}
 
-	return tokenString, expiresAt, nil
+func (tm *TokenManager) GenerateAccessToken(userId int64, username, email string) (string, time.Time, error) {
+	return tm.signer.Sign(userId, username, email, jwtauth.TokenAccess, accessTokenTTL)
 }
 
 func (tm *TokenManager) GenerateRefreshToken(userID int64, username, email string) (string, time.Time, error) {
-	expiresAt := time.Now().Add(7 * 24 * time.Hour)
-
-	claims := Claims{
-		UserId:   userID,
-		Username: username,
-		Email:    email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(tm.secretKey))
-	if err != nil {
-		return "", time.Time{}, err
-	}
-
-	return tokenString, expiresAt, nil
+	return tm.signer.Sign(userID, username, email, jwtauth.TokenRefresh, refreshTokenTTL)
 }
 
+// ValidateToken accepts access and refresh tokens alike
 func (tm *TokenManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(tm.secretKey), nil
-	})
-
-	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrExpiredToken
-		}
-		return nil, ErrInvalidToken
-	}
+	return tm.verifier.Verify(tokenString, jwtauth.AnyToken)
+}
 
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return nil, ErrInvalidToken
-	}
+func (tm *TokenManager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return tm.verifier.Verify(tokenString, jwtauth.TokenAccess)
+}
 
-	return claims, nil
+func (tm *TokenManager) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return tm.verifier.Verify(tokenString, jwtauth.TokenRefresh)
 }
@@ -32,7 +32,7 @@ func TestGenerateAndValidateAccessToken(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected validation error: %v", err)
 	}
-	if claims.UserId != userId || claims.Username != username || claims.Email != email {
+	if claims.UserID != userId || claims.Username != username || claims.Email != email {
 		t.Errorf("claims mismatch: got %+v", claims)
 	}
 }
@@ -61,7 +61,7 @@ func TestGenerateAndValidateRefreshToken(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected validation error: %v", err)
 	}
-	if claims.UserId != userId || claims.Username != username || claims.Email != email {
+	if claims.UserID != userId || claims.Username != username || claims.Email != email {
 		t.Errorf("claims mismatch: got %+v", claims)
 	}
 }
@@ -90,7 +90,7 @@ func TestValidateToken_Expired(t *testing.T) {
 
 	expiredTime := time.Now().Add(-1 * time.Minute)
 	claims := Claims{
-		UserId:   1,
+		UserID:   1,
 		Username: "expired",
 		Email:    "exp@example.com",
 		RegisteredClaims: jwt.RegisteredClaims{